@@ -97,11 +97,44 @@ func (a *app) OpenDirectoryDialog(options OpenDialogOptions) (string, error) {
 }
 
 func (a *app) ExternalInstallMod(modID, version string) {
-	wailsRuntime.EventsEmit(common.AppContext, "externalInstallMod", modID, version)
+	common.EventsEmit("externalInstallMod", modID, version)
 }
 
 func (a *app) ExternalImportProfile(path string) {
-	wailsRuntime.EventsEmit(common.AppContext, "externalImportProfile", path)
+	common.EventsEmit("externalImportProfile", path)
+}
+
+// Confirm asks the user a yes or no question in a native dialog, for actions requested from outside the app
+func (a *app) Confirm(title string, message string) (bool, error) {
+	if common.AppContext == nil {
+		return false, fmt.Errorf("cannot ask for confirmation without a window")
+	}
+	result, err := wailsRuntime.MessageDialog(common.AppContext, wailsRuntime.MessageDialogOptions{
+		Type:          wailsRuntime.QuestionDialog,
+		Title:         title,
+		Message:       message,
+		Buttons:       []string{"Yes", "No"},
+		DefaultButton: "No",
+		CancelButton:  "No",
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to show confirmation dialog: %w", err)
+	}
+	return result == "Yes", nil
+}
+
+func (a *app) ShowError(title string, message string) {
+	if common.AppContext == nil {
+		return
+	}
+	_, err := wailsRuntime.MessageDialog(common.AppContext, wailsRuntime.MessageDialogOptions{
+		Type:    wailsRuntime.ErrorDialog,
+		Title:   title,
+		Message: message,
+	})
+	if err != nil {
+		slog.Error("failed to show error dialog", slog.Any("error", err))
+	}
 }
 
 func (a *app) Show() {
@@ -1,14 +1,19 @@
 package backend
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/app"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/ficsitcli"
 )
 
+var errCancelled = errors.New("cancelled by the user")
+
 func ProcessArguments(args []string) {
 	if len(args) < 1 {
 		return
@@ -16,8 +21,9 @@ func ProcessArguments(args []string) {
 	if strings.HasPrefix(args[0], "smmanager://") {
 		uri := args[0]
 		err := handleURI(uri)
-		if err != nil {
+		if err != nil && !errors.Is(err, errCancelled) {
 			slog.Error("failed to handle smmanager:// URI", slog.Any("error", err), slog.String("uri", uri))
+			app.App.ShowError("Failed to open link", err.Error())
 		}
 	} else {
 		err := handleFile(args[0])
@@ -28,24 +34,186 @@ func ProcessArguments(args []string) {
 	app.App.Show()
 }
 
+// handleURI runs the action of a smmanager:// link. Except for installing a single mod, which has its own dialog,
+// the user is asked to confirm every action, since the links can come from any website.
+//
+//	smmanager://install?modID=<mod>&version=<version>
+//	smmanager://installMods?mod=<mod>[@<version>]&mod=...
+//	smmanager://importProfile?url=<url>
+//	smmanager://importProfile?code=<share code>
+//	smmanager://setProfile?name=<profile>
+//	smmanager://addServer?path=<path>
+//	smmanager://launch?install=<path>
 func handleURI(uri string) error {
 	u, err := url.Parse(uri)
 	if err != nil {
 		return fmt.Errorf("failed to parse URI: %w", err)
 	}
+	query := u.Query()
 	switch u.Host {
 	case "install":
-		modID := u.Query().Get("modID")
-		version := u.Query().Get("version")
+		modID := query.Get("modID")
+		version := query.Get("version")
 		app.App.ExternalInstallMod(modID, version)
 		return nil
+	case "installMods":
+		return handleInstallMods(query["mod"])
+	case "importProfile":
+		if code := query.Get("code"); code != "" {
+			return handleImportProfileCode(code)
+		}
+		return handleImportProfile(query.Get("url"))
+	case "setProfile":
+		return handleSetProfile(query.Get("name"))
+	case "addServer":
+		return handleAddServer(query.Get("path"))
+	case "launch":
+		return handleLaunch(query.Get("install"))
 	default:
 		return fmt.Errorf("unknown URI action " + u.Host)
 	}
 }
 
+func confirm(title string, message string) error {
+	ok, err := app.App.Confirm(title, message)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if !ok {
+		return errCancelled
+	}
+	return nil
+}
+
+func handleInstallMods(mods []string) error {
+	if len(mods) == 0 {
+		return fmt.Errorf("no mods to install")
+	}
+	selectedInstallation := ficsitcli.FicsitCLI.GetSelectedInstall()
+	if selectedInstallation == nil {
+		return fmt.Errorf("no installation selected")
+	}
+
+	err := confirm("Install mods", fmt.Sprintf("Install these mods to profile %s of %s?\n\n%s", selectedInstallation.Profile, selectedInstallation.Path, strings.Join(mods, "\n")))
+	if err != nil {
+		return err
+	}
+
+	// The operations are queued together, so they are applied in one batch
+	var wg sync.WaitGroup
+	errs := make([]error, len(mods))
+	for i, mod := range mods {
+		wg.Add(1)
+		go func(i int, mod string) {
+			defer wg.Done()
+			modReference, version, hasVersion := strings.Cut(mod, "@")
+			if hasVersion {
+				errs[i] = ficsitcli.FicsitCLI.InstallModVersion(modReference, version)
+			} else {
+				errs[i] = ficsitcli.FicsitCLI.InstallMod(modReference)
+			}
+		}(i, mod)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func handleImportProfile(profileURL string) error {
+	u, err := url.Parse(profileURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid profile URL %s", profileURL)
+	}
+
+	err = confirm("Import profile", fmt.Sprintf("Download a profile from %s?\n\n%s\n\nYou will be able to review it before it is imported.", u.Host, profileURL))
+	if err != nil {
+		return err
+	}
+
+	path, err := ficsitcli.FicsitCLI.ResolveProfileSource(u.String())
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	app.App.ExternalImportProfile(path)
+	return nil
+}
+
+func handleImportProfileCode(code string) error {
+	// The code is decoded before asking, so that invalid codes are reported without a confirmation.
+	// Only share codes are accepted here, links must go through the url parameter, which asks before downloading.
+	path, err := ficsitcli.FicsitCLI.ResolveProfileShareCode(code)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	err = confirm("Import profile", "Import the profile from the share code?\n\nYou will be able to review it before it is imported.")
+	if err != nil {
+		return err
+	}
+	app.App.ExternalImportProfile(path)
+	return nil
+}
+
+func handleSetProfile(name string) error {
+	if ficsitcli.FicsitCLI.GetProfile(name) == nil {
+		return fmt.Errorf("profile %s not found", name)
+	}
+	selectedInstallation := ficsitcli.FicsitCLI.GetSelectedInstall()
+	if selectedInstallation == nil {
+		return fmt.Errorf("no installation selected")
+	}
+
+	err := confirm("Switch profile", fmt.Sprintf("Switch %s to profile %s?", selectedInstallation.Path, name))
+	if err != nil {
+		return err
+	}
+	return ficsitcli.FicsitCLI.SetProfile(name) //nolint:wrapcheck
+}
+
+func handleAddServer(path string) error {
+	if path == "" {
+		return fmt.Errorf("no server path")
+	}
+
+	displayPath := path
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		displayPath = u.Redacted()
+	}
+
+	err := confirm("Add server", fmt.Sprintf("Add the dedicated server %s?", displayPath))
+	if err != nil {
+		return err
+	}
+	return ficsitcli.FicsitCLI.AddRemoteServer(path) //nolint:wrapcheck
+}
+
+func handleLaunch(install string) error {
+	if install != "" && ficsitcli.FicsitCLI.GetInstallation(install) == nil {
+		return fmt.Errorf("installation %s not found", install)
+	}
+	if install == "" {
+		selectedInstallation := ficsitcli.FicsitCLI.GetSelectedInstall()
+		if selectedInstallation == nil {
+			return fmt.Errorf("no installation selected")
+		}
+		install = selectedInstallation.Path
+	}
+
+	err := confirm("Launch game", fmt.Sprintf("Launch Satisfactory from %s?", install))
+	if err != nil {
+		return err
+	}
+
+	err = ficsitcli.FicsitCLI.SelectInstall(install)
+	if err != nil {
+		return fmt.Errorf("failed to select installation: %w", err)
+	}
+	// Launching waits for the game to exit
+	go ficsitcli.FicsitCLI.LaunchGame()
+	return nil
+}
+
 func handleFile(path string) error {
-	if strings.HasSuffix(path, ".smmprofile") {
+	if strings.HasSuffix(path, ".smmprofile") || strings.HasSuffix(path, ".smmbundle") {
 		println(path)
 		app.App.ExternalImportProfile(path)
 		return nil
@@ -35,13 +35,13 @@ func Init() {
 		enabled: shouldUseUpdater(),
 	}
 	Updater.Updater.UpdateFound.On(func(update updater.PendingUpdate) {
-		wailsRuntime.EventsEmit(common.AppContext, "updateAvailable", update.Version.String(), update.Changelogs)
+		common.EventsEmit("updateAvailable", update.Version.String(), update.Changelogs)
 	})
 	Updater.Updater.DownloadProgress.On(func(progress updater.UpdateDownloadProgress) {
-		wailsRuntime.EventsEmit(common.AppContext, "updateDownloadProgress", progress.BytesDownloaded, progress.BytesTotal)
+		common.EventsEmit("updateDownloadProgress", progress.BytesDownloaded, progress.BytesTotal)
 	})
 	Updater.Updater.UpdateReady.On(func(interface{}) {
-		wailsRuntime.EventsEmit(common.AppContext, "updateReady")
+		common.EventsEmit("updateReady")
 	})
 }
 
@@ -1,5 +1,41 @@
 package common
 
-import "context"
+import (
+	"context"
+	"sync"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
 
 var AppContext context.Context
+
+type EventListener func(eventName string, data ...interface{})
+
+var (
+	eventListenersLock sync.RWMutex
+	eventListeners     []EventListener
+)
+
+// AddEventListener registers a listener that receives every event sent to the frontend,
+// even when running without a frontend
+func AddEventListener(listener EventListener) {
+	eventListenersLock.Lock()
+	defer eventListenersLock.Unlock()
+	eventListeners = append(eventListeners, listener)
+}
+
+// EventsEmit sends the event to the frontend and the registered listeners.
+// The frontend is skipped when running without one, such as in headless mode, where wails would exit instead.
+func EventsEmit(eventName string, data ...interface{}) {
+	eventListenersLock.RLock()
+	listeners := eventListeners
+	eventListenersLock.RUnlock()
+	for _, listener := range listeners {
+		listener(eventName, data...)
+	}
+
+	if AppContext == nil {
+		return
+	}
+	wailsRuntime.EventsEmit(AppContext, eventName, data...)
+}
@@ -0,0 +1,335 @@
+package ficsitcli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"github.com/spf13/viper"
+
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/utils"
+)
+
+var changelogCacheFileName = "changelogs.json"
+
+// The API returns at most this many versions per request
+const changelogPageSize = 100
+
+// Upper bound on the requests made for the changelogs of a single mod
+const maxChangelogPages = 10
+
+const modChangelogsQuery = `query ModChangelogs($modId: String!, $filter: VersionFilter) {
+	mod: getModByIdOrReference(modIdOrReference: $modId) {
+		versions(filter: $filter) {
+			version
+			changelog
+			created_at
+		}
+	}
+}`
+
+type VersionChangelog struct {
+	Version   string     `json:"version"`
+	Changelog string     `json:"changelog"`
+	Date      *time.Time `json:"date,omitempty"`
+}
+
+type modChangelogsResponse struct {
+	Mod *struct {
+		Versions []struct {
+			Version   string    `json:"version"`
+			Changelog string    `json:"changelog"`
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"versions"`
+	} `json:"mod"`
+}
+
+// changelogCache keeps the changelogs fetched from the API, so that they are available in offline mode
+type changelogCache struct {
+	lock       sync.Mutex
+	loaded     bool
+	changed    bool
+	changelogs map[string]map[string]VersionChangelog
+}
+
+var changelogs = &changelogCache{}
+
+// load reads the cache file the first time it is needed. Must be called with the lock held.
+func (c *changelogCache) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.changelogs = make(map[string]map[string]VersionChangelog)
+
+	cacheFile, err := os.ReadFile(filepath.Join(viper.GetString("smm-cache-dir"), changelogCacheFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("failed to read changelog cache", slog.Any("error", err))
+		}
+		return
+	}
+
+	if err := json.Unmarshal(cacheFile, &c.changelogs); err != nil {
+		slog.Error("failed to unmarshal changelog cache", slog.Any("error", err))
+		c.changelogs = make(map[string]map[string]VersionChangelog)
+	}
+}
+
+// get returns a copy of the cached changelogs of the mod
+func (c *changelogCache) get(mod string) map[string]VersionChangelog {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.load()
+	modChangelogs := maps.Clone(c.changelogs[mod])
+	if modChangelogs == nil {
+		modChangelogs = make(map[string]VersionChangelog)
+	}
+	return modChangelogs
+}
+
+// add stores the fetched changelogs of the mod, and returns a copy of all its cached changelogs
+func (c *changelogCache) add(mod string, fetched map[string]VersionChangelog) map[string]VersionChangelog {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.load()
+	modChangelogs, ok := c.changelogs[mod]
+	if !ok {
+		modChangelogs = make(map[string]VersionChangelog)
+		c.changelogs[mod] = modChangelogs
+	}
+	maps.Copy(modChangelogs, fetched)
+	if len(fetched) > 0 {
+		c.changed = true
+	}
+	return maps.Clone(modChangelogs)
+}
+
+// save writes the cache file, if anything was added since it was last written
+func (c *changelogCache) save() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.changed {
+		return nil
+	}
+	cacheFile, err := utils.JSONMarshal(c.changelogs, 2)
+	if err != nil {
+		return fmt.Errorf("failed to marshal changelog cache: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(viper.GetString("smm-cache-dir"), changelogCacheFileName), cacheFile, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write changelog cache: %w", err)
+	}
+	c.changed = false
+	return nil
+}
+
+// getChangelogs returns the known changelogs of the mod, keyed by version.
+// Unless offline, or both since and until are already cached, the changelogs are fetched from the API until the version since is reached.
+// The versions are fetched newest first, so when since and until are cached, so are all the versions between them.
+func (f *ficsitCLI) getChangelogs(ctx context.Context, mod string, since string, until string) map[string]VersionChangelog {
+	l := slog.With(slog.String("task", "getChangelogs"), slog.String("mod", mod))
+
+	cached := changelogs.get(mod)
+	if f.ficsitCli.Provider.IsOffline() {
+		return cached
+	}
+	_, hasSince := cached[since]
+	_, hasUntil := cached[until]
+	if hasSince && hasUntil {
+		return cached
+	}
+
+	fetched := make(map[string]VersionChangelog)
+	sinceVersion, sinceErr := semver.NewVersion(since)
+	for page := 0; page < maxChangelogPages; page++ {
+		var response modChangelogsResponse
+		err := f.ficsitCli.APIClient.MakeRequest(ctx, &graphql.Request{
+			OpName: "ModChangelogs",
+			Query:  modChangelogsQuery,
+			Variables: map[string]interface{}{
+				"modId": mod,
+				"filter": map[string]interface{}{
+					"limit":    changelogPageSize,
+					"offset":   page * changelogPageSize,
+					"order_by": "created_at",
+					"order":    "desc",
+				},
+			},
+		}, &graphql.Response{Data: &response})
+		if err != nil {
+			l.Warn("failed to fetch changelogs", slog.Any("error", err))
+			break
+		}
+		if response.Mod == nil {
+			break
+		}
+
+		reachedSince := false
+		for _, version := range response.Mod.Versions {
+			date := version.CreatedAt
+			fetched[version.Version] = VersionChangelog{
+				Version:   version.Version,
+				Changelog: version.Changelog,
+				Date:      &date,
+			}
+			if v, err := semver.NewVersion(version.Version); err == nil && sinceErr == nil && v.Compare(sinceVersion) <= 0 {
+				reachedSince = true
+			}
+		}
+		if reachedSince || len(response.Mod.Versions) < changelogPageSize {
+			break
+		}
+	}
+
+	return changelogs.add(mod, fetched)
+}
+
+// addUpdateDetails fills in the changelogs and requirement changes of the updates.
+// Missing information is left empty, it does not prevent updating.
+func (f *ficsitCLI) addUpdateDetails(ctx context.Context, updates []Update) {
+	smlVersions, err := f.ficsitCli.Provider.SMLVersions(ctx)
+	if err != nil {
+		slog.Warn("failed to get SML versions", slog.Any("error", err))
+	}
+
+	for i := range updates {
+		update := &updates[i]
+		update.Changelogs = []VersionChangelog{}
+
+		if update.Item == "SML" {
+			for _, smlVersion := range smlVersions {
+				if smlVersion.Version == update.CurrentVersion {
+					update.CurrentGameVersion = smlVersion.SatisfactoryVersion
+				}
+				if smlVersion.Version == update.NewVersion {
+					update.NewGameVersion = smlVersion.SatisfactoryVersion
+				}
+			}
+		} else {
+			versions, err := f.ficsitCli.Provider.ModVersionsWithDependencies(ctx, update.Item)
+			if err != nil {
+				slog.Warn("failed to get mod versions", slog.String("mod", update.Item), slog.Any("error", err))
+				continue
+			}
+			for _, version := range versions {
+				if version.Version == update.CurrentVersion {
+					update.CurrentSMLVersion = getSMLCondition(version)
+					update.CurrentGameVersion = minimumGameVersion(update.CurrentSMLVersion, smlVersions)
+				}
+				if version.Version == update.NewVersion {
+					update.NewSMLVersion = getSMLCondition(version)
+					update.NewGameVersion = minimumGameVersion(update.NewSMLVersion, smlVersions)
+				}
+			}
+			update.RaisesSMLVersion = raisesMinimum(update.CurrentSMLVersion, update.NewSMLVersion, smlVersions)
+		}
+		update.RaisesGameVersion = update.NewGameVersion > update.CurrentGameVersion
+
+		currentVersion, err := semver.NewVersion(update.CurrentVersion)
+		if err != nil {
+			continue
+		}
+		newVersion, err := semver.NewVersion(update.NewVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, changelog := range f.getChangelogs(ctx, update.Item, update.CurrentVersion, update.NewVersion) {
+			v, err := semver.NewVersion(changelog.Version)
+			if err != nil {
+				continue
+			}
+			if v.Compare(currentVersion) <= 0 || v.Compare(newVersion) > 0 {
+				continue
+			}
+			update.Changelogs = append(update.Changelogs, changelog)
+			if changelog.Version == update.NewVersion {
+				update.ReleaseDate = changelog.Date
+			}
+		}
+		sort.Slice(update.Changelogs, func(a, b int) bool {
+			va, _ := semver.NewVersion(update.Changelogs[a].Version)
+			vb, _ := semver.NewVersion(update.Changelogs[b].Version)
+			return va.Compare(vb) > 0
+		})
+	}
+
+	if err := changelogs.save(); err != nil {
+		slog.Warn("failed to save changelog cache", slog.Any("error", err))
+	}
+}
+
+func getSMLCondition(version resolver.ModVersion) string {
+	for _, dependency := range version.Dependencies {
+		if dependency.ModID == "SML" {
+			return dependency.Condition
+		}
+	}
+	return ""
+}
+
+// minimumSMLVersion returns the oldest SML version that satisfies the condition
+func minimumSMLVersion(condition string, smlVersions []resolver.SMLVersion) *resolver.SMLVersion {
+	constraint, err := semver.NewConstraint(condition)
+	if err != nil {
+		return nil
+	}
+	var minimum *resolver.SMLVersion
+	var minimumVersion semver.Version
+	for i, smlVersion := range smlVersions {
+		v, err := semver.NewVersion(smlVersion.Version)
+		if err != nil || !constraint.Contains(v) {
+			continue
+		}
+		if minimum == nil || v.Compare(minimumVersion) < 0 {
+			minimum = &smlVersions[i]
+			minimumVersion = v
+		}
+	}
+	return minimum
+}
+
+func minimumGameVersion(smlCondition string, smlVersions []resolver.SMLVersion) int {
+	minimum := minimumSMLVersion(smlCondition, smlVersions)
+	if minimum == nil {
+		return 0
+	}
+	return minimum.SatisfactoryVersion
+}
+
+func raisesMinimum(currentCondition string, newCondition string, smlVersions []resolver.SMLVersion) bool {
+	if newCondition == "" || currentCondition == newCondition {
+		return false
+	}
+	newMinimum := minimumSMLVersion(newCondition, smlVersions)
+	if newMinimum == nil {
+		return false
+	}
+	currentMinimum := minimumSMLVersion(currentCondition, smlVersions)
+	if currentMinimum == nil {
+		return currentCondition == ""
+	}
+	newVersion, err := semver.NewVersion(newMinimum.Version)
+	if err != nil {
+		return false
+	}
+	currentVersion, err := semver.NewVersion(currentMinimum.Version)
+	if err != nil {
+		return false
+	}
+	return newVersion.Compare(currentVersion) > 0
+}
@@ -0,0 +1,465 @@
+package ficsitcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/mircearoata/pubgrub-go/pubgrub"
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"github.com/spf13/viper"
+)
+
+// Package names the resolver uses for the profile itself and for the game
+const (
+	resolverRootPkg = "$$root$$"
+	resolverGamePkg = "FactoryGame"
+)
+
+type ConflictRequirement struct {
+	// The mod that has the requirement, empty if it is the profile itself
+	ModReference string `json:"modReference"`
+	// The versions of the mod that have the requirement
+	Versions   string `json:"versions"`
+	Dependency string `json:"dependency"`
+	Condition  string `json:"condition"`
+}
+
+type ConflictSuggestionType string
+
+const (
+	ConflictSuggestionRemove     ConflictSuggestionType = "remove"
+	ConflictSuggestionDowngrade  ConflictSuggestionType = "downgrade"
+	ConflictSuggestionUpgrade    ConflictSuggestionType = "upgrade"
+	ConflictSuggestionUpdateGame ConflictSuggestionType = "updateGame"
+	// The mod is not installed, or already is at the suggested version, so only its version constraint changes
+	ConflictSuggestionSetVersion ConflictSuggestionType = "setVersion"
+)
+
+var AllConflictSuggestionTypes = []struct {
+	Value  ConflictSuggestionType
+	TSName string
+}{
+	{ConflictSuggestionRemove, "REMOVE"},
+	{ConflictSuggestionDowngrade, "DOWNGRADE"},
+	{ConflictSuggestionUpgrade, "UPGRADE"},
+	{ConflictSuggestionUpdateGame, "UPDATE_GAME"},
+	{ConflictSuggestionSetVersion, "SET_VERSION"},
+}
+
+type ConflictSuggestion struct {
+	Type         ConflictSuggestionType `json:"type"`
+	ModReference string                 `json:"modReference,omitempty"`
+	Version      string                 `json:"version,omitempty"`
+}
+
+type ConflictReport struct {
+	// The resolver's own explanation
+	Message string `json:"message"`
+	// Mods involved in the conflict
+	Mods []string `json:"mods"`
+	// Display names of the involved mods
+	Names        map[string]string     `json:"names"`
+	Requirements []ConflictRequirement `json:"requirements"`
+	// Mods for which no version matches what is required, keyed by mod reference
+	Unavailable map[string]string `json:"unavailable"`
+	GameVersion int               `json:"gameVersion"`
+	// Whether the installed game version is part of the conflict
+	GameVersionConflict bool `json:"gameVersionConflict"`
+	// The lowest game version that satisfies what SML requires, if the game version is part of the conflict
+	RequiredGameVersion int                  `json:"requiredGameVersion,omitempty"`
+	Suggestions         []ConflictSuggestion `json:"suggestions"`
+}
+
+// asConflictReport returns the explanation of err if it is a resolution failure, otherwise nil
+func (f *ficsitCLI) asConflictReport(err error, installation *cli.Installation) *ConflictReport {
+	var solvingError resolver.DependencyResolverError
+	if !errors.As(err, &solvingError) {
+		return nil
+	}
+	gameVersion, gameVersionErr := installation.GetGameVersion(f.ficsitCli)
+	if gameVersionErr != nil {
+		slog.Warn("failed to get game version", slog.Any("error", gameVersionErr))
+	}
+	var lockfile *resolver.LockFile
+	if !installation.Vanilla {
+		lockfile, _ = installation.LockFile(f.ficsitCli)
+	}
+	return f.explainConflict(solvingError, gameVersion, lockfile)
+}
+
+// ExplainProfileConflicts resolves the profile of the selected installation and explains why it fails to resolve.
+// With latest, all mods are resolved to their latest version, like when checking for updates.
+// Returns nil if the profile resolves.
+func (f *ficsitCLI) ExplainProfileConflicts(latest bool) (*ConflictReport, error) {
+	l := slog.With(slog.String("task", "explainProfileConflicts"), slog.Bool("latest", latest))
+
+	selectedInstallation := f.GetSelectedInstall()
+	if selectedInstallation == nil {
+		return nil, fmt.Errorf("no installation selected")
+	}
+
+	profile := f.GetProfile(selectedInstallation.Profile)
+	if profile == nil {
+		return nil, fmt.Errorf("profile %s not found", selectedInstallation.Profile)
+	}
+
+	gameVersion, err := selectedInstallation.GetGameVersion(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get game version", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get game version: %w", err)
+	}
+
+	lockfile, err := selectedInstallation.LockFile(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get lockfile", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get lockfile: %w", err)
+	}
+
+	resolveProfile := copyProfile(profile)
+	resolveLockfile := lockfile
+	if latest {
+		for modReference, profileMod := range resolveProfile.Mods {
+			if !f.isModPinned(profile.Name, modReference) {
+				profileMod.Version = ">=0.0.0"
+				resolveProfile.Mods[modReference] = profileMod
+			}
+		}
+		resolveLockfile = nil
+	}
+
+	res := resolver.NewDependencyResolver(f.ficsitCli.Provider, viper.GetString("api-base"))
+	_, err = resolveProfile.Resolve(res, resolveLockfile, gameVersion)
+	if err == nil {
+		return nil, nil
+	}
+
+	var solvingError resolver.DependencyResolverError
+	if !errors.As(err, &solvingError) {
+		l.Error("failed to resolve dependencies", slog.Any("error", err))
+		return nil, err //nolint:wrapcheck
+	}
+
+	return f.explainConflict(solvingError, gameVersion, lockfile), nil
+}
+
+func (f *ficsitCLI) explainConflict(solvingError resolver.DependencyResolverError, gameVersion int, lockfile *resolver.LockFile) *ConflictReport {
+	ctx := context.Background()
+
+	report := analyzeConflict(solvingError, gameVersion)
+
+	for _, mod := range report.Mods {
+		if mod == "SML" {
+			continue
+		}
+		if name, err := f.ficsitCli.Provider.GetModName(ctx, mod); err == nil && name != nil {
+			report.Names[mod] = name.Name
+		}
+	}
+
+	report.RequiredGameVersion = f.requiredGameVersion(ctx, report)
+	report.Suggestions = f.suggestConflictResolutions(ctx, report, lockfile)
+
+	return report
+}
+
+// analyzeConflict builds the report from the incompatibilities that led to the failure, without names or suggestions
+func analyzeConflict(solvingError resolver.DependencyResolverError, gameVersion int) *ConflictReport {
+	report := &ConflictReport{
+		Message:      solvingError.Error(),
+		Mods:         []string{},
+		Names:        map[string]string{},
+		Requirements: []ConflictRequirement{},
+		Unavailable:  map[string]string{},
+		GameVersion:  gameVersion,
+		Suggestions:  []ConflictSuggestion{},
+	}
+
+	mods := map[string]bool{}
+	addMod := func(pkg string) {
+		if pkg != resolverRootPkg && pkg != resolverGamePkg {
+			mods[pkg] = true
+		}
+	}
+
+	visited := map[*pubgrub.Incompatibility]bool{}
+	var walk func(incompatibility *pubgrub.Incompatibility)
+	walk = func(incompatibility *pubgrub.Incompatibility) {
+		if incompatibility == nil || visited[incompatibility] {
+			return
+		}
+		visited[incompatibility] = true
+
+		if len(incompatibility.Causes()) > 0 {
+			for _, cause := range incompatibility.Causes() {
+				walk(cause)
+			}
+			return
+		}
+
+		// Only the external incompatibilities, those that come directly from the profile, mods, or game, are reported
+		terms := incompatibility.Terms()
+		switch len(terms) {
+		case 1:
+			term := terms[0]
+			switch term.Dependency() {
+			case resolverRootPkg:
+			case resolverGamePkg:
+				report.GameVersionConflict = true
+			default:
+				addMod(term.Dependency())
+				report.Unavailable[term.Dependency()] = term.Constraint().String()
+			}
+		case 2:
+			depender, dependency := terms[0], terms[1]
+			if !depender.Positive() {
+				depender, dependency = dependency, depender
+			}
+			addMod(depender.Dependency())
+			addMod(dependency.Dependency())
+
+			if dependency.Dependency() == resolverGamePkg {
+				report.GameVersionConflict = true
+			}
+
+			requirement := ConflictRequirement{
+				Dependency: dependency.Dependency(),
+				Condition:  dependency.Constraint().String(),
+			}
+			if depender.Dependency() != resolverRootPkg {
+				requirement.ModReference = depender.Dependency()
+				requirement.Versions = depender.Constraint().String()
+			}
+			report.Requirements = append(report.Requirements, requirement)
+		}
+	}
+	walk(solvingError.Cause())
+
+	for mod := range mods {
+		report.Mods = append(report.Mods, mod)
+		report.Names[mod] = mod
+	}
+	sort.Strings(report.Mods)
+	sort.SliceStable(report.Requirements, func(i, j int) bool {
+		if report.Requirements[i].Dependency != report.Requirements[j].Dependency {
+			return report.Requirements[i].Dependency < report.Requirements[j].Dependency
+		}
+		return report.Requirements[i].ModReference < report.Requirements[j].ModReference
+	})
+
+	return report
+}
+
+// requiredGameVersion returns the lowest game version newer than the installed one that satisfies the game requirements in the conflict,
+// out of the game versions required by the SML releases, or 0 if there is none.
+// The resolver uses the game version as the major of a semver version, so it can be checked against the requirements directly.
+func (f *ficsitCLI) requiredGameVersion(ctx context.Context, report *ConflictReport) int {
+	if !report.GameVersionConflict {
+		return 0
+	}
+
+	constraint := semver.AnyConstraint
+	found := false
+	for _, requirement := range report.Requirements {
+		if requirement.Dependency != resolverGamePkg {
+			continue
+		}
+		c, err := semver.NewConstraint(requirement.Condition)
+		if err != nil {
+			continue
+		}
+		constraint = constraint.Intersect(c)
+		found = true
+	}
+	if !found {
+		return 0
+	}
+
+	smlVersions, err := f.ficsitCli.Provider.SMLVersions(ctx)
+	if err != nil {
+		slog.Warn("failed to get SML versions", slog.Any("error", err))
+		return 0
+	}
+	required := 0
+	for _, smlVersion := range smlVersions {
+		gameVersion := smlVersion.SatisfactoryVersion
+		if gameVersion <= report.GameVersion || (required != 0 && gameVersion >= required) {
+			continue
+		}
+		v, err := semver.NewVersion(strconv.Itoa(gameVersion))
+		if err != nil || !constraint.Contains(v) {
+			continue
+		}
+		required = gameVersion
+	}
+	return required
+}
+
+func (f *ficsitCLI) suggestConflictResolutions(ctx context.Context, report *ConflictReport, lockfile *resolver.LockFile) []ConflictSuggestion {
+	suggestions := []ConflictSuggestion{}
+
+	if report.GameVersionConflict && report.RequiredGameVersion != 0 {
+		suggestions = append(suggestions, ConflictSuggestion{
+			Type:    ConflictSuggestionUpdateGame,
+			Version: strconv.Itoa(report.RequiredGameVersion),
+		})
+	}
+
+	// What the other side of each requirement allows for the dependency
+	allowed := map[string][]semver.Constraint{}
+	for _, requirement := range report.Requirements {
+		c, err := semver.NewConstraint(requirement.Condition)
+		if err != nil {
+			continue
+		}
+		allowed[requirement.Dependency] = append(allowed[requirement.Dependency], c)
+	}
+
+	var compatibleSML []semver.Version
+	if report.GameVersionConflict {
+		smlVersions, err := f.ficsitCli.Provider.SMLVersions(ctx)
+		if err == nil {
+			for _, smlVersion := range smlVersions {
+				if smlVersion.SatisfactoryVersion > report.GameVersion {
+					continue
+				}
+				if v, err := semver.NewVersion(smlVersion.Version); err == nil {
+					compatibleSML = append(compatibleSML, v)
+				}
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, requirement := range report.Requirements {
+		mod := requirement.ModReference
+		if mod == "" || mod == "SML" || seen[mod] {
+			continue
+		}
+
+		// The constraints on the dependency, from everything other than this mod
+		var others []semver.Constraint
+		for _, other := range report.Requirements {
+			if other.Dependency != requirement.Dependency || other.ModReference == mod {
+				continue
+			}
+			if c, err := semver.NewConstraint(other.Condition); err == nil {
+				others = append(others, c)
+			}
+		}
+
+		version := f.findCompatibleVersion(ctx, mod, requirement.Dependency, others, allowed[mod], compatibleSML)
+		if version == "" {
+			continue
+		}
+		seen[mod] = true
+
+		suggestionType := ConflictSuggestionSetVersion
+		if lockfile != nil {
+			if lockedMod, ok := lockfile.Mods[mod]; ok {
+				locked, err1 := semver.NewVersion(lockedMod.Version)
+				suggested, err2 := semver.NewVersion(version)
+				if err1 == nil && err2 == nil {
+					switch cmp := suggested.Compare(locked); {
+					case cmp > 0:
+						suggestionType = ConflictSuggestionUpgrade
+					case cmp < 0:
+						suggestionType = ConflictSuggestionDowngrade
+					}
+				}
+			}
+		}
+		suggestions = append(suggestions, ConflictSuggestion{
+			Type:         suggestionType,
+			ModReference: mod,
+			Version:      version,
+		})
+	}
+
+	// Removing any of the mods in the profile that are part of the conflict is always an option
+	for _, requirement := range report.Requirements {
+		if requirement.ModReference != "" || requirement.Dependency == "SML" || requirement.Dependency == resolverGamePkg {
+			continue
+		}
+		suggestions = append(suggestions, ConflictSuggestion{
+			Type:         ConflictSuggestionRemove,
+			ModReference: requirement.Dependency,
+		})
+	}
+
+	return suggestions
+}
+
+// findCompatibleVersion returns the newest version of mod that is allowed by modConstraints,
+// and whose requirement on dependency can be met together with others.
+// For SML, the versions compatible with the game are used instead when given.
+func (f *ficsitCLI) findCompatibleVersion(ctx context.Context, mod string, dependency string, others []semver.Constraint, modConstraints []semver.Constraint, compatibleSML []semver.Version) string {
+	versions, err := f.ficsitCli.Provider.ModVersionsWithDependencies(ctx, mod)
+	if err != nil {
+		return ""
+	}
+
+	var best *semver.Version
+	bestRaw := ""
+	for _, version := range versions {
+		v, err := semver.NewVersion(version.Version)
+		if err != nil {
+			continue
+		}
+		if best != nil && v.Compare(*best) <= 0 {
+			continue
+		}
+
+		allowedByOthers := true
+		for _, c := range modConstraints {
+			if !c.Contains(v) {
+				allowedByOthers = false
+				break
+			}
+		}
+		if !allowedByOthers {
+			continue
+		}
+
+		compatible := true
+		for _, versionDependency := range version.Dependencies {
+			if versionDependency.ModID != dependency {
+				continue
+			}
+			condition, err := semver.NewConstraint(versionDependency.Condition)
+			if err != nil {
+				compatible = false
+				break
+			}
+			if dependency == "SML" && compatibleSML != nil {
+				compatible = false
+				for _, smlVersion := range compatibleSML {
+					if condition.Contains(smlVersion) {
+						compatible = true
+						break
+					}
+				}
+				break
+			}
+			for _, other := range others {
+				if condition.Intersect(other).IsEmpty() {
+					compatible = false
+					break
+				}
+			}
+		}
+		if !compatible {
+			continue
+		}
+
+		best = &v
+		bestRaw = version.Version
+	}
+
+	return bestRaw
+}
@@ -0,0 +1,65 @@
+package ficsitcli
+
+import (
+	"context"
+	"sync"
+
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"golang.org/x/sync/errgroup"
+)
+
+// getLockedModVersions fetches the version information (dependencies and targets) of each mod in the lockfile.
+// Mods for which the information is not available (such as SML, or mods missing from the provider) are left out.
+func (f *ficsitCLI) getLockedModVersions(ctx context.Context, lockfile *resolver.LockFile) map[string]resolver.ModVersion {
+	result := make(map[string]resolver.ModVersion, len(lockfile.Mods))
+	var lock sync.Mutex
+
+	errg, errgCtx := errgroup.WithContext(ctx)
+	errg.SetLimit(10)
+	for modReference, lockedMod := range lockfile.Mods {
+		modReference := modReference
+		lockedMod := lockedMod
+		errg.Go(func() error {
+			versions, err := f.ficsitCli.Provider.ModVersionsWithDependencies(errgCtx, modReference)
+			if err != nil {
+				// Treat missing information as no information, rather than failing everything
+				return nil
+			}
+			for _, version := range versions {
+				if version.Version == lockedMod.Version {
+					lock.Lock()
+					result[modReference] = version
+					lock.Unlock()
+					break
+				}
+			}
+			return nil
+		})
+	}
+	_ = errg.Wait()
+
+	return result
+}
+
+// getDependents returns, for each mod in the lockfile, the mods in the lockfile that depend on it
+func getDependents(lockfile *resolver.LockFile, versions map[string]resolver.ModVersion) map[string][]string {
+	dependents := make(map[string][]string)
+	for modReference, version := range versions {
+		for _, dependency := range version.Dependencies {
+			if _, ok := lockfile.Mods[dependency.ModID]; !ok {
+				continue
+			}
+			dependents[dependency.ModID] = append(dependents[dependency.ModID], modReference)
+		}
+	}
+	return dependents
+}
+
+func getTargetSize(version resolver.ModVersion, targetName string) int64 {
+	for _, target := range version.Targets {
+		if string(target.TargetName) == targetName {
+			return target.Size
+		}
+	}
+	return 0
+}
@@ -0,0 +1,279 @@
+package ficsitcli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+type DependencyGraphNode struct {
+	ModReference string `json:"modReference"`
+	Version      string `json:"version"`
+	Size         int64  `json:"size"`
+	// Whether the mod is enabled in the profile, rather than only installed as a dependency
+	InProfile bool `json:"inProfile"`
+}
+
+type DependencyGraphEdge struct {
+	// The mod that has the dependency
+	From string `json:"from"`
+	// The mod that is depended on
+	To        string `json:"to"`
+	Condition string `json:"condition"`
+	Optional  bool   `json:"optional"`
+}
+
+type DependencyGraph struct {
+	Nodes []DependencyGraphNode `json:"nodes"`
+	Edges []DependencyGraphEdge `json:"edges"`
+}
+
+type WhyInstalled struct {
+	ModReference string `json:"modReference"`
+	InProfile    bool   `json:"inProfile"`
+	// The installed mods that depend on this mod
+	Dependents []DependencyGraphEdge `json:"dependents"`
+	// The shortest chain of dependencies from a mod in the profile to this mod, starting with the profile mod
+	Chain []string `json:"chain"`
+}
+
+type RemovalImpact struct {
+	ModReference string `json:"modReference"`
+	// Mods that require this mod, so it stays installed as a dependency even after removing it from the profile
+	StillRequiredBy []string `json:"stillRequiredBy"`
+	// Mods that will be uninstalled, including this mod unless it is still required
+	Removed []string `json:"removed"`
+	// Installed mods that optionally depend on the removed mods
+	OptionalDependents []DependencyGraphEdge `json:"optionalDependents"`
+}
+
+// GetDependencyGraph returns the mods installed in the selected installation and the dependencies between them
+func (f *ficsitCLI) GetDependencyGraph() (*DependencyGraph, error) {
+	l := slog.With(slog.String("task", "getDependencyGraph"))
+
+	graph, err := f.buildDependencyGraph()
+	if err != nil {
+		l.Error("failed to build dependency graph", slog.Any("error", err))
+		return nil, err
+	}
+	return graph, nil
+}
+
+func (f *ficsitCLI) buildDependencyGraph() (*DependencyGraph, error) {
+	selectedInstallation := f.GetSelectedInstall()
+	if selectedInstallation == nil {
+		return nil, fmt.Errorf("no installation selected")
+	}
+
+	lockfile, err := f.getInstalledLockfile(selectedInstallation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	platform, err := selectedInstallation.GetPlatform(f.ficsitCli)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get platform: %w", err)
+	}
+
+	profile := f.GetProfile(selectedInstallation.Profile)
+
+	versions := f.getLockedModVersions(context.Background(), lockfile)
+
+	graph := &DependencyGraph{
+		Nodes: make([]DependencyGraphNode, 0, len(lockfile.Mods)),
+		Edges: []DependencyGraphEdge{},
+	}
+
+	for modReference, lockedMod := range lockfile.Mods {
+		node := DependencyGraphNode{
+			ModReference: modReference,
+			Version:      lockedMod.Version,
+			InProfile:    profile != nil && profile.IsModEnabled(modReference),
+		}
+		version, ok := versions[modReference]
+		if ok {
+			node.Size = getTargetSize(version, platform.TargetName)
+			for _, dependency := range version.Dependencies {
+				if _, ok := lockfile.Mods[dependency.ModID]; !ok {
+					// Optional dependencies that are not installed
+					continue
+				}
+				graph.Edges = append(graph.Edges, DependencyGraphEdge{
+					From:      modReference,
+					To:        dependency.ModID,
+					Condition: dependency.Condition,
+					Optional:  dependency.Optional,
+				})
+			}
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].ModReference < graph.Nodes[j].ModReference
+	})
+	sortEdges(graph.Edges)
+
+	return graph, nil
+}
+
+func sortEdges(edges []DependencyGraphEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
+
+// WhyInstalled explains why the mod is installed in the selected installation
+func (f *ficsitCLI) WhyInstalled(mod string) (*WhyInstalled, error) {
+	l := slog.With(slog.String("task", "whyInstalled"), slog.String("mod", mod))
+
+	graph, err := f.buildDependencyGraph()
+	if err != nil {
+		l.Error("failed to build dependency graph", slog.Any("error", err))
+		return nil, err
+	}
+
+	nodes := make(map[string]DependencyGraphNode, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes[node.ModReference] = node
+	}
+	node, ok := nodes[mod]
+	if !ok {
+		return nil, fmt.Errorf("mod %s is not installed", mod)
+	}
+
+	result := &WhyInstalled{
+		ModReference: mod,
+		InProfile:    node.InProfile,
+		Dependents:   []DependencyGraphEdge{},
+		Chain:        []string{},
+	}
+
+	dependents := make(map[string][]string)
+	for _, edge := range graph.Edges {
+		if edge.To == mod {
+			result.Dependents = append(result.Dependents, edge)
+		}
+		if !edge.Optional {
+			dependents[edge.To] = append(dependents[edge.To], edge.From)
+		}
+	}
+
+	// Walk up the dependents breadth first, so that the first profile mod reached is the closest one
+	previous := map[string]string{mod: ""}
+	queue := []string{mod}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if nodes[current].InProfile {
+			for step := current; step != ""; step = previous[step] {
+				result.Chain = append(result.Chain, step)
+			}
+			break
+		}
+		next := dependents[current]
+		sort.Strings(next)
+		for _, dependent := range next {
+			if _, ok := previous[dependent]; ok {
+				continue
+			}
+			previous[dependent] = current
+			queue = append(queue, dependent)
+		}
+	}
+
+	return result, nil
+}
+
+// GetRemovalImpact returns what would change if the mod was removed from the profile of the selected installation
+func (f *ficsitCLI) GetRemovalImpact(mod string) (*RemovalImpact, error) {
+	l := slog.With(slog.String("task", "getRemovalImpact"), slog.String("mod", mod))
+
+	graph, err := f.buildDependencyGraph()
+	if err != nil {
+		l.Error("failed to build dependency graph", slog.Any("error", err))
+		return nil, err
+	}
+
+	return removalImpact(graph, mod)
+}
+
+func removalImpact(graph *DependencyGraph, mod string) (*RemovalImpact, error) {
+	found := false
+	roots := make([]string, 0)
+	for _, node := range graph.Nodes {
+		if node.ModReference == mod {
+			found = true
+			continue
+		}
+		if node.InProfile {
+			roots = append(roots, node.ModReference)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("mod %s is not installed", mod)
+	}
+
+	dependencies := make(map[string][]string)
+	for _, edge := range graph.Edges {
+		if !edge.Optional {
+			dependencies[edge.From] = append(dependencies[edge.From], edge.To)
+		}
+	}
+
+	// Everything reachable from the remaining profile mods stays installed
+	kept := make(map[string]bool)
+	queue := roots
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if kept[current] {
+			continue
+		}
+		kept[current] = true
+		queue = append(queue, dependencies[current]...)
+	}
+
+	impact := &RemovalImpact{
+		ModReference:       mod,
+		StillRequiredBy:    []string{},
+		Removed:            []string{},
+		OptionalDependents: []DependencyGraphEdge{},
+	}
+
+	for _, node := range graph.Nodes {
+		if !kept[node.ModReference] {
+			impact.Removed = append(impact.Removed, node.ModReference)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		if edge.To != mod || !kept[edge.From] {
+			continue
+		}
+		if edge.Optional {
+			continue
+		}
+		impact.StillRequiredBy = append(impact.StillRequiredBy, edge.From)
+	}
+
+	removed := make(map[string]bool, len(impact.Removed))
+	for _, modReference := range impact.Removed {
+		removed[modReference] = true
+	}
+	for _, edge := range graph.Edges {
+		if edge.Optional && removed[edge.To] && kept[edge.From] {
+			impact.OptionalDependents = append(impact.OptionalDependents, edge)
+		}
+	}
+
+	sort.Strings(impact.Removed)
+	sort.Strings(impact.StillRequiredBy)
+	sortEdges(impact.OptionalDependents)
+
+	return impact, nil
+}
@@ -0,0 +1,248 @@
+package ficsitcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"github.com/spf13/viper"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/utils"
+)
+
+// Number of entries kept per installation, older ones are dropped
+const maxInstallHistoryEntries = 20
+
+var installHistoryFileName = "installHistory.json"
+
+type InstallHistoryOperation struct {
+	Type string `json:"type"`
+	Item string `json:"item"`
+}
+
+type InstallHistoryEntry struct {
+	ID         string                    `json:"id"`
+	Time       time.Time                 `json:"time"`
+	Operations []InstallHistoryOperation `json:"operations"`
+	Profile    string                    `json:"profile"`
+	// Mods of the profile at the time, so that the same constraints are used when reverting
+	ProfileMods map[string]cli.ProfileMod `json:"profileMods"`
+	Vanilla     bool                      `json:"vanilla"`
+	LockFile    *resolver.LockFile        `json:"lockfile"`
+}
+
+type installHistory struct {
+	lock    sync.Mutex
+	entries map[string][]InstallHistoryEntry
+}
+
+func loadInstallHistory() *installHistory {
+	history := &installHistory{
+		entries: make(map[string][]InstallHistoryEntry),
+	}
+
+	historyFile, err := os.ReadFile(filepath.Join(viper.GetString("smm-local-dir"), installHistoryFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("failed to read install history", slog.Any("error", err))
+		}
+		return history
+	}
+
+	if err := json.Unmarshal(historyFile, &history.entries); err != nil {
+		slog.Error("failed to unmarshal install history", slog.Any("error", err))
+		history.entries = make(map[string][]InstallHistoryEntry)
+	}
+
+	return history
+}
+
+func (h *installHistory) save() error {
+	historyFile, err := utils.JSONMarshal(h.entries, 2)
+	if err != nil {
+		return fmt.Errorf("failed to marshal install history: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(viper.GetString("smm-local-dir"), installHistoryFileName), historyFile, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to write install history: %w", err)
+	}
+	return nil
+}
+
+// recordInstallHistory adds the current state of the installation to its history.
+// Nothing is recorded if the state is the same as the latest entry.
+func (f *ficsitCLI) recordInstallHistory(installation *cli.Installation, operations []InstallHistoryOperation) {
+	l := slog.With(slog.String("task", "recordInstallHistory"), slog.String("install", installation.Path))
+
+	lockfile, err := installation.LockFile(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get lockfile", slog.Any("error", err))
+		return
+	}
+	if lockfile == nil {
+		lockfile = resolver.NewLockfile()
+	}
+
+	var profileMods map[string]cli.ProfileMod
+	if profile := f.GetProfile(installation.Profile); profile != nil {
+		profileMods = maps.Clone(profile.Mods)
+	}
+
+	entry := InstallHistoryEntry{
+		ID:          strconv.FormatInt(time.Now().UnixNano(), 10),
+		Time:        time.Now(),
+		Operations:  operations,
+		Profile:     installation.Profile,
+		ProfileMods: profileMods,
+		Vanilla:     installation.Vanilla,
+		LockFile:    lockfile,
+	}
+
+	f.history.lock.Lock()
+	entries := f.history.entries[installation.Path]
+	if len(entries) > 0 {
+		latest := entries[len(entries)-1]
+		if latest.Profile == entry.Profile && latest.Vanilla == entry.Vanilla && reflect.DeepEqual(latest.LockFile, entry.LockFile) && reflect.DeepEqual(latest.ProfileMods, entry.ProfileMods) {
+			f.history.lock.Unlock()
+			return
+		}
+	}
+	entries = append(entries, entry)
+	if len(entries) > maxInstallHistoryEntries {
+		entries = slices.Clone(entries[len(entries)-maxInstallHistoryEntries:])
+	}
+	f.history.entries[installation.Path] = entries
+	err = f.history.save()
+	f.history.lock.Unlock()
+
+	if err != nil {
+		l.Error("failed to save install history", slog.Any("error", err))
+	}
+
+	appCommon.EventsEmit("installHistory", installation.Path, f.GetInstallHistory(installation.Path))
+}
+
+// GetInstallHistory returns the history of the installation, newest entry first
+func (f *ficsitCLI) GetInstallHistory(path string) []InstallHistoryEntry {
+	f.history.lock.Lock()
+	defer f.history.lock.Unlock()
+
+	entries := slices.Clone(f.history.entries[path])
+	if entries == nil {
+		return []InstallHistoryEntry{}
+	}
+	slices.Reverse(entries)
+	return entries
+}
+
+// RevertInstallTo restores the profile and the exact mod versions the installation had at the time of the history entry.
+// The profile mods are changed back as well, which also affects other installations using the same profile.
+func (f *ficsitCLI) RevertInstallTo(path string, entryID string) error {
+	l := slog.With(slog.String("task", "revertInstallTo"), slog.String("install", path), slog.String("entry", entryID))
+
+	if f.GetInstallation(path) == nil {
+		l.Error("installation not found")
+		return fmt.Errorf("installation %s not found", path)
+	}
+
+	f.history.lock.Lock()
+	entryIdx := slices.IndexFunc(f.history.entries[path], func(entry InstallHistoryEntry) bool {
+		return entry.ID == entryID
+	})
+	var entry InstallHistoryEntry
+	if entryIdx != -1 {
+		entry = f.history.entries[path][entryIdx]
+	}
+	f.history.lock.Unlock()
+
+	if entryIdx == -1 {
+		l.Error("history entry not found")
+		return fmt.Errorf("history entry %s not found", entryID)
+	}
+
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "revertInstall",
+			Item:         "__revert_install__",
+			Installation: path,
+		},
+		apply: func(installation *cli.Installation) error {
+			profile := f.GetProfile(entry.Profile)
+			if profile == nil {
+				// The profile was deleted since, so it is recreated from the entry
+				var err error
+				profile, err = f.ficsitCli.Profiles.AddProfile(entry.Profile)
+				if err != nil {
+					l.Error("failed to add profile", slog.Any("error", err))
+					return fmt.Errorf("failed to add profile: %w", err)
+				}
+			}
+			profile.Mods = maps.Clone(entry.ProfileMods)
+			if profile.Mods == nil {
+				profile.Mods = make(map[string]cli.ProfileMod)
+			}
+
+			err := f.ficsitCli.Profiles.Save()
+			if err != nil {
+				l.Error("failed to save profiles", slog.Any("error", err))
+			}
+
+			err = installation.SetProfile(f.ficsitCli, entry.Profile)
+			if err != nil {
+				l.Error("failed to set profile", slog.Any("error", err))
+				return fmt.Errorf("failed to set profile: %w", err)
+			}
+			installation.Vanilla = entry.Vanilla
+
+			err = f.ficsitCli.Installations.Save()
+			if err != nil {
+				l.Error("failed to save installations", slog.Any("error", err))
+			}
+
+			err = f.lockVersions(installation, entry.LockFile)
+			if err != nil {
+				l.Error("failed to write lockfile", slog.Any("error", err))
+				return err
+			}
+
+			f.EmitGlobals()
+
+			return nil
+		},
+	})
+}
+
+// lockVersions writes the lockfile of the installation, for operations that must install exactly its mod versions.
+// The resolver prefers the versions in the lockfile, so the validation that follows the operation keeps them.
+func (f *ficsitCLI) lockVersions(installation *cli.Installation, lockfile *resolver.LockFile) error {
+	err := installation.WriteLockFile(f.ficsitCli, lockfile)
+	if err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+func (f *ficsitCLI) deleteInstallHistory(path string) {
+	f.history.lock.Lock()
+	defer f.history.lock.Unlock()
+
+	if _, ok := f.history.entries[path]; !ok {
+		return
+	}
+	delete(f.history.entries, path)
+	err := f.history.save()
+	if err != nil {
+		slog.Error("failed to save install history", slog.Any("error", err))
+	}
+}
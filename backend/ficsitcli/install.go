@@ -0,0 +1,320 @@
+package ficsitcli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	ficsitcache "github.com/satisfactorymodding/ficsit-cli/cli/cache"
+	"github.com/satisfactorymodding/ficsit-cli/cli/disk"
+	ficsitUtils "github.com/satisfactorymodding/ficsit-cli/utils"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"golang.org/x/sync/errgroup"
+)
+
+// install does the same as cli.Installation.Install, but stops as soon as ctx is cancelled.
+// Mods that were being extracted when the install was stopped are removed,
+// so that they do not stay around half-extracted.
+// setPhase is called when the install moves to resolving or removing, the download and extraction phases are reported through updates.
+func (f *ficsitCLI) install(ctx context.Context, installation *cli.Installation, updates chan<- cli.InstallUpdate, setPhase func(ProgressPhase)) error {
+	var forwarders sync.WaitGroup
+	defer func() {
+		forwarders.Wait()
+		close(updates)
+	}()
+
+	if err := installation.Validate(f.ficsitCli); err != nil {
+		return fmt.Errorf("failed to validate installation: %w", err)
+	}
+
+	platform, err := installation.GetPlatform(f.ficsitCli)
+	if err != nil {
+		return fmt.Errorf("failed to detect platform: %w", err)
+	}
+
+	setPhase(ProgressPhaseResolving)
+
+	lockfile := resolver.NewLockfile()
+	if !installation.Vanilla {
+		lockfile, err = installation.ResolveProfile(f.ficsitCli)
+		if err != nil {
+			return fmt.Errorf("failed to resolve lockfile: %w", err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	d, err := installation.GetDisk()
+	if err != nil {
+		return fmt.Errorf("failed to get disk: %w", err)
+	}
+	cd := &contextDisk{Disk: d, ctx: ctx}
+
+	modsDirectory := filepath.Join(installation.BasePath(), "FactoryGame", "Mods")
+	if err := cd.MkDir(modsDirectory); err != nil {
+		return fmt.Errorf("failed creating Mods directory: %w", err)
+	}
+
+	dir, err := cd.ReadDir(modsDirectory)
+	if err != nil {
+		return fmt.Errorf("failed to read mods directory: %w", err)
+	}
+
+	setPhase(ProgressPhaseRemoving)
+
+	for _, entry := range dir {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, ok := lockfile.Mods[entry.Name()]; ok {
+			continue
+		}
+		modDir := filepath.Join(modsDirectory, entry.Name())
+		exists, err := cd.Exists(filepath.Join(modDir, ".smm"))
+		if err != nil {
+			return fmt.Errorf("failed to check if %s is managed by SMM: %w", entry.Name(), err)
+		}
+		if exists {
+			slog.Info("deleting mod", slog.String("mod_reference", entry.Name()))
+			if err := cd.Remove(modDir); err != nil {
+				return fmt.Errorf("failed to delete mod directory: %w", err)
+			}
+		}
+	}
+
+	slog.Info("starting installation", slog.Int("concurrency", cap(f.downloadSemaphore)), slog.String("path", installation.Path))
+
+	var incompleteLock sync.Mutex
+	incomplete := make(map[string]bool)
+
+	errg, errgCtx := errgroup.WithContext(ctx)
+	for modReference, version := range lockfile.Mods {
+		modReference := modReference
+		version := version
+		errg.Go(func() error {
+			target, ok := version.Targets[platform.TargetName]
+			if !ok {
+				return fmt.Errorf("%s@%s not available for %s", modReference, version.Version, platform.TargetName)
+			}
+
+			// Only install if a link is provided, otherwise assume mod is already installed
+			if target.Link == "" {
+				return nil
+			}
+
+			cacheKey := modReference + "_" + version.Version + "_" + platform.TargetName + ".zip"
+			file, size, err := f.downloadToCache(errgCtx, cacheKey, target.Hash, target.Link, forwardModUpdates(&forwarders, updates, cli.InstallUpdateTypeModDownload, modReference, version.Version))
+			if err != nil {
+				return fmt.Errorf("failed to download %s@%s: %w", modReference, version.Version, err)
+			}
+			defer file.Close()
+
+			incompleteLock.Lock()
+			incomplete[modReference] = true
+			incompleteLock.Unlock()
+
+			modDisk := &contextDisk{Disk: d, ctx: errgCtx}
+			extractUpdates := forwardModUpdates(&forwarders, updates, cli.InstallUpdateTypeModExtract, modReference, version.Version)
+			err = ficsitUtils.ExtractMod(file, size, filepath.Join(modsDirectory, modReference), target.Hash, extractUpdates, modDisk)
+			close(extractUpdates)
+			if err != nil {
+				return fmt.Errorf("failed to extract %s@%s: %w", modReference, version.Version, err)
+			}
+
+			incompleteLock.Lock()
+			delete(incomplete, modReference)
+			incompleteLock.Unlock()
+
+			updates <- cli.InstallUpdate{
+				Type: cli.InstallUpdateTypeModComplete,
+				Item: cli.InstallUpdateItem{
+					Mod:     modReference,
+					Version: version.Version,
+				},
+			}
+			return nil
+		})
+	}
+
+	installErr := errg.Wait()
+
+	if installErr != nil {
+		for modReference := range incomplete {
+			slog.Info("removing partially extracted mod", slog.String("mod_reference", modReference))
+			if err := d.Remove(filepath.Join(modsDirectory, modReference)); err != nil {
+				slog.Error("failed to remove partially extracted mod", slog.String("mod_reference", modReference), slog.Any("error", err))
+			}
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr //nolint:wrapcheck
+		}
+		return fmt.Errorf("failed to install mods: %w", installErr)
+	}
+
+	slog.Info("installation completed", slog.String("path", installation.Path))
+
+	return nil
+}
+
+// forwardModUpdates returns a channel whose progress updates are sent to updates as InstallUpdates for the mod.
+// The returned channel must be closed by the caller, and wg waited for before closing updates.
+func forwardModUpdates(wg *sync.WaitGroup, updates chan<- cli.InstallUpdate, updateType cli.InstallUpdateType, modReference string, version string) chan ficsitUtils.GenericProgress {
+	progress := make(chan ficsitUtils.GenericProgress)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for p := range progress {
+			updates <- cli.InstallUpdate{
+				Type: updateType,
+				Item: cli.InstallUpdateItem{
+					Mod:     modReference,
+					Version: version,
+				},
+				Progress: p,
+			}
+		}
+	}()
+	return progress
+}
+
+// downloadToCache downloads the file to the ficsit-cli download cache with ficsitcache.DownloadOrCache, unless it is already there.
+// DownloadOrCache retries failed downloads and adds the downloaded file to the offline cache, but cannot be stopped,
+// so once ctx is cancelled no new download starts, and a download that already started finishes in the background.
+// The number of concurrent downloads is limited across all installs running at the same time.
+func (f *ficsitCLI) downloadToCache(ctx context.Context, cacheKey string, hash string, url string, updates chan ficsitUtils.GenericProgress) (*os.File, int64, error) {
+	defer close(updates)
+
+	select {
+	case f.downloadSemaphore <- 1:
+	case <-ctx.Done():
+		return nil, 0, ctx.Err() //nolint:wrapcheck
+	}
+
+	// DownloadOrCache can send one more update after it returns with an error, the buffer keeps it from blocking
+	progress := make(chan ficsitUtils.GenericProgress, 1)
+	done := make(chan downloadResult, 1)
+	go func() {
+		defer func() { <-f.downloadSemaphore }()
+		file, size, err := ficsitcache.DownloadOrCache(cacheKey, hash, url, progress, nil)
+		done <- downloadResult{file: file, size: size, err: err}
+	}()
+
+	for {
+		select {
+		case p := <-progress:
+			updates <- p
+		case result := <-done:
+			select {
+			case p := <-progress:
+				updates <- p
+			default:
+			}
+			return result.file, result.size, result.err
+		case <-ctx.Done():
+			go discardDownload(progress, done)
+			return nil, 0, ctx.Err() //nolint:wrapcheck
+		}
+	}
+}
+
+type downloadResult struct {
+	file *os.File
+	size int64
+	err  error
+}
+
+// discardDownload waits for a download that is no longer needed, so that its progress updates do not block it
+func discardDownload(progress <-chan ficsitUtils.GenericProgress, done <-chan downloadResult) {
+	for {
+		select {
+		case <-progress:
+		case result := <-done:
+			select {
+			case <-progress:
+			default:
+			}
+			if result.file != nil {
+				result.file.Close()
+			}
+			return
+		}
+	}
+}
+
+// contextDisk fails all operations once ctx is cancelled, which stops extractions midway
+type contextDisk struct {
+	disk.Disk
+	ctx context.Context
+}
+
+func (d *contextDisk) Exists(path string) (bool, error) {
+	if err := d.ctx.Err(); err != nil {
+		return false, err //nolint:wrapcheck
+	}
+	return d.Disk.Exists(path) //nolint:wrapcheck
+}
+
+func (d *contextDisk) Read(path string) ([]byte, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return d.Disk.Read(path) //nolint:wrapcheck
+}
+
+func (d *contextDisk) Write(path string, data []byte) error {
+	if err := d.ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+	return d.Disk.Write(path, data) //nolint:wrapcheck
+}
+
+func (d *contextDisk) Remove(path string) error {
+	if err := d.ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+	return d.Disk.Remove(path) //nolint:wrapcheck
+}
+
+func (d *contextDisk) MkDir(path string) error {
+	if err := d.ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+	return d.Disk.MkDir(path) //nolint:wrapcheck
+}
+
+func (d *contextDisk) ReadDir(path string) ([]disk.Entry, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return d.Disk.ReadDir(path) //nolint:wrapcheck
+}
+
+func (d *contextDisk) Open(path string, flag int) (io.WriteCloser, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	w, err := d.Disk.Open(path, flag)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return &contextWriter{WriteCloser: w, ctx: d.ctx}, nil
+}
+
+type contextWriter struct {
+	io.WriteCloser
+	ctx context.Context
+}
+
+func (w *contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+	return w.WriteCloser.Write(p) //nolint:wrapcheck
+}
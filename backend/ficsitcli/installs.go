@@ -68,6 +68,38 @@ func (f *ficsitCLI) GetInstallationsMetadata() map[string]installationMetadata {
 	return rawMap
 }
 
+type InstallationInfo struct {
+	Path     string               `json:"path"`
+	Profile  string               `json:"profile"`
+	Vanilla  bool                 `json:"vanilla"`
+	Selected bool                 `json:"selected"`
+	State    InstallState         `json:"state"`
+	Info     *common.Installation `json:"info,omitempty"`
+}
+
+// GetInstallationsInfo returns the installations together with their settings and metadata
+func (f *ficsitCLI) GetInstallationsInfo() []InstallationInfo {
+	metadata := f.GetInstallationsMetadata()
+	selected := f.GetSelectedInstall()
+
+	installs := []InstallationInfo{}
+	for _, path := range f.GetInstallations() {
+		installation := f.GetInstallation(path)
+		if installation == nil {
+			continue
+		}
+		installs = append(installs, InstallationInfo{
+			Path:     path,
+			Profile:  installation.Profile,
+			Vanilla:  installation.Vanilla,
+			Selected: selected != nil && selected.Path == path,
+			State:    metadata[path].State,
+			Info:     metadata[path].Info,
+		})
+	}
+	return installs
+}
+
 func (f *ficsitCLI) GetCurrentInstallationMetadata() installationMetadata {
 	meta, _ := f.installationMetadata.Load(f.ficsitCli.Installations.SelectedInstallation)
 	return meta
@@ -97,39 +129,47 @@ func (f *ficsitCLI) SelectInstall(path string) error {
 	if f.ficsitCli.Installations.SelectedInstallation == path {
 		return nil
 	}
-	installation := f.ficsitCli.Installations.GetInstallation(path)
-	if installation == nil {
+	if f.ficsitCli.Installations.GetInstallation(path) == nil {
 		l.Error("failed to find installation")
 		return fmt.Errorf("installation %s not found", path)
 	}
 
-	f.ficsitCli.Installations.SelectedInstallation = path
-	err := f.ficsitCli.Installations.Save()
-	if err != nil {
-		l.Error("failed to save selected installation", slog.Any("error", err))
-	}
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "selectInstall",
+			Item:         "__select_install__",
+			Installation: path,
+		},
+		apply: func(installation *cli.Installation) error {
+			f.ficsitCli.Installations.SelectedInstallation = installation.Path
+			err := f.ficsitCli.Installations.Save()
+			if err != nil {
+				l.Error("failed to save selected installation", slog.Any("error", err))
+			}
 
-	selectedInstallation := f.GetSelectedInstall()
+			f.EmitGlobals()
 
-	f.EmitGlobals()
+			return nil
+		},
+	})
+}
 
-	f.progress = &Progress{
-		Item:     "__select_install__",
-		Message:  "Validating install",
-		Progress: -1,
+// SelectInstallTemporarily selects the installation for this process only, without validating it.
+// Operations that save the installations also save the selection, so the returned function
+// puts back the previous selection and saves it again.
+func (f *ficsitCLI) SelectInstallTemporarily(path string) (func(), error) {
+	if !f.isValidInstall(path) || f.ficsitCli.Installations.GetInstallation(path) == nil {
+		return nil, fmt.Errorf("invalid installation: %s", path)
 	}
 
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
-
-	installErr := f.validateInstall(selectedInstallation, "__select_install__")
-
-	if installErr != nil {
-		l.Error("failed to validate install", slog.Any("error", installErr))
-		return installErr
-	}
-	return nil
+	previous := f.ficsitCli.Installations.SelectedInstallation
+	f.ficsitCli.Installations.SelectedInstallation = path
+	return func() {
+		f.ficsitCli.Installations.SelectedInstallation = previous
+		if err := f.ficsitCli.Installations.Save(); err != nil {
+			slog.Error("failed to save selected installation", slog.Any("error", err))
+		}
+	}, nil
 }
 
 func (f *ficsitCLI) GetSelectedInstall() *cli.Installation {
@@ -143,41 +183,27 @@ func (f *ficsitCLI) SetModsEnabled(enabled bool) error {
 		slog.Error("no installation selected")
 		return fmt.Errorf("no installation selected")
 	}
-	l := slog.With(slog.String("task", "setModsEnabled"), slog.Bool("enabled", enabled), slog.String("install", selectedInstallation.Path))
 
-	var message string
-	if enabled {
-		message = "Enabling mods"
-	} else {
-		message = "Disabling mods"
-	}
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "setModsEnabled",
+			Item:         "__toggle_mods__",
+			Installation: selectedInstallation.Path,
+		},
+		apply: func(installation *cli.Installation) error {
+			l := slog.With(slog.String("task", "setModsEnabled"), slog.Bool("enabled", enabled), slog.String("install", installation.Path))
 
-	selectedInstallation.Vanilla = !enabled
-	err := f.ficsitCli.Installations.Save()
-	if err != nil {
-		l.Error("failed to save vanilla state of install", slog.Any("error", err))
-	}
-
-	f.EmitGlobals()
-
-	f.progress = &Progress{
-		Item:     "__toggle_mods__",
-		Message:  message,
-		Progress: -1,
-	}
-
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
-
-	installErr := f.validateInstall(selectedInstallation, "__toggle_mods__")
+			installation.Vanilla = !enabled
+			err := f.ficsitCli.Installations.Save()
+			if err != nil {
+				l.Error("failed to save vanilla state of install", slog.Any("error", err))
+			}
 
-	if installErr != nil {
-		l.Error("failed to validate install", slog.Any("error", installErr))
-		return installErr
-	}
+			f.EmitGlobals()
 
-	return nil
+			return nil
+		},
+	})
 }
 
 func (f *ficsitCLI) GetModsEnabled() bool {
@@ -242,3 +268,15 @@ func (f *ficsitCLI) LaunchGame() {
 		return
 	}
 }
+
+func (f *ficsitCLI) GetInstallLockfile(path string) (*resolver.LockFile, error) {
+	installation := f.GetInstallation(path)
+	if installation == nil {
+		return nil, fmt.Errorf("installation %s not found", path)
+	}
+	lockfile, err := installation.LockFile(f.ficsitCli)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return lockfile, nil
+}
@@ -0,0 +1,218 @@
+package ficsitcli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+)
+
+type ApplyProfileResult struct {
+	Installation string `json:"installation"`
+	Success      bool   `json:"success"`
+	Error        string `json:"error,omitempty"`
+	RolledBack   bool   `json:"rolledBack"`
+}
+
+// ApplyProfileToInstalls sets the profile on all the given installations and validates them concurrently.
+// Installations that fail are rolled back on their own, without affecting the others.
+// Once the operation runs, the outcome of each installation, including its error, is in the results,
+// and the error is only returned for failures before any installation is changed.
+func (f *ficsitCLI) ApplyProfileToInstalls(profile string, paths []string) ([]ApplyProfileResult, error) {
+	l := slog.With(slog.String("task", "applyProfileToInstalls"), slog.String("profile", profile))
+
+	if f.GetProfile(profile) == nil {
+		l.Error("profile not found")
+		return nil, fmt.Errorf("profile %s not found", profile)
+	}
+	uniquePaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !slices.Contains(uniquePaths, path) {
+			uniquePaths = append(uniquePaths, path)
+		}
+	}
+	paths = uniquePaths
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no installations specified")
+	}
+	for _, path := range paths {
+		if f.GetInstallation(path) == nil {
+			l.Error("installation not found", slog.String("install", path))
+			return nil, fmt.Errorf("installation %s not found", path)
+		}
+	}
+
+	results := make([]ApplyProfileResult, len(paths))
+	ran := false
+
+	err := f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type: "applyProfileToInstalls",
+			Item: "__apply_profile__",
+		},
+		run: func(ctx context.Context) error {
+			ran = true
+			return f.applyProfileToInstalls(ctx, profile, paths, results)
+		},
+	})
+	if !ran {
+		return nil, err
+	}
+	if err != nil {
+		l.Warn("profile not applied to all installations", slog.Any("error", err))
+	}
+	return results, nil
+}
+
+func (f *ficsitCLI) applyProfileToInstalls(ctx context.Context, profile string, paths []string, results []ApplyProfileResult) error {
+	l := slog.With(slog.String("task", "applyProfileToInstalls"), slog.String("profile", profile))
+
+	// Saving profiles and installations is not safe to do concurrently
+	var saveLock sync.Mutex
+
+	var completedLock sync.Mutex
+	completed := 0
+	reportCompleted := func() {
+		completedLock.Lock()
+		completed++
+		f.setProgress(&Progress{
+			Item:          "__apply_profile__",
+			Phase:         ProgressPhaseApplying,
+			Progress:      float64(completed) / float64(len(paths)),
+			Installations: CountProgress{Completed: int64(completed), Total: int64(len(paths))},
+		})
+		completedLock.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		i := i
+		path := path
+		results[i].Installation = path
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer reportCompleted()
+			defer f.setInstallProgress(path, nil)
+
+			installation := f.GetInstallation(path)
+			if installation == nil {
+				results[i].Error = fmt.Sprintf("installation %s not found", path)
+				return
+			}
+
+			err := f.applyProfileToInstall(ctx, installation, profile, &saveLock, &results[i])
+			if err != nil {
+				l.Error("failed to apply profile", slog.String("install", path), slog.Any("error", err))
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Success = true
+		}()
+	}
+	wg.Wait()
+
+	f.EmitGlobals()
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return ErrOperationCancelled
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to apply profile to %d of %d installations", failed, len(paths))
+	}
+	return nil
+}
+
+func (f *ficsitCLI) applyProfileToInstall(ctx context.Context, installation *cli.Installation, profile string, saveLock *sync.Mutex, result *ApplyProfileResult) error {
+	l := slog.With(slog.String("task", "applyProfileToInstall"), slog.String("profile", profile), slog.String("install", installation.Path))
+
+	setProgress := func(p *Progress) {
+		f.setInstallProgress(installation.Path, p)
+	}
+
+	saveLock.Lock()
+	snapshot, err := f.snapshotInstallation(installation)
+	if err != nil {
+		saveLock.Unlock()
+		return fmt.Errorf("failed to snapshot installation: %w", err)
+	}
+	err = installation.SetProfile(f.ficsitCli, profile)
+	if err == nil {
+		err = f.ficsitCli.Installations.Save()
+		if err != nil {
+			l.Error("failed to save installations", slog.Any("error", err))
+			err = nil
+		}
+	}
+	saveLock.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to set profile: %w", err)
+	}
+
+	setProgress(&Progress{
+		Item:     "__apply_profile__",
+		Phase:    ProgressPhaseResolving,
+		Progress: -1,
+	})
+
+	installErr := f.validateInstallWithProgress(ctx, installation, "__apply_profile__", setProgress)
+	if installErr == nil {
+		f.recordInstallHistory(installation, []InstallHistoryOperation{{Type: "applyProfileToInstalls", Item: "__apply_profile__"}})
+		return nil
+	}
+
+	l.Error("failed to validate installation, rolling back", slog.Any("error", installErr))
+
+	setProgress(&Progress{
+		Item:     "__apply_profile__",
+		Phase:    ProgressPhaseRollingBack,
+		Progress: -1,
+	})
+
+	saveLock.Lock()
+	err = f.restoreSnapshot(installation, snapshot)
+	saveLock.Unlock()
+	if err == nil {
+		// The rollback must not be cancelled, otherwise the installation could be left in an inconsistent state
+		err = f.validateInstallWithProgress(context.Background(), installation, "__apply_profile__", setProgress)
+	}
+	if err != nil {
+		l.Error("failed to roll back", slog.Any("error", err))
+	} else {
+		result.RolledBack = true
+	}
+
+	return installErr
+}
+
+func (f *ficsitCLI) setInstallProgress(path string, p *Progress) {
+	if p != nil {
+		p.Operations = f.runningOperations()
+		f.installProgress.Store(path, p)
+	} else {
+		f.installProgress.Delete(path)
+	}
+	appCommon.EventsEmit("installProgress", path, p)
+}
+
+// GetInstallProgress returns the progress of each installation that is being validated as part of a multi-installation operation
+func (f *ficsitCLI) GetInstallProgress() map[string]*Progress {
+	progress := make(map[string]*Progress)
+	f.installProgress.Range(func(key string, value *Progress) bool {
+		progress[key] = value
+		return true
+	})
+	return progress
+}
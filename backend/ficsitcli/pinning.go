@@ -0,0 +1,318 @@
+package ficsitcli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
+)
+
+// SetModConstraint changes the version range the mod can be installed with in the profile.
+// If the selected installation uses the profile, it is validated with the new constraint.
+func (f *ficsitCLI) SetModConstraint(profileName string, mod string, constraint string) error {
+	l := slog.With(slog.String("task", "setModConstraint"), slog.String("profile", profileName), slog.String("mod", mod), slog.String("constraint", constraint))
+
+	profile := f.GetProfile(profileName)
+	if profile == nil {
+		l.Error("profile not found")
+		return fmt.Errorf("profile %s not found", profileName)
+	}
+	if _, ok := profile.Mods[mod]; !ok {
+		l.Error("mod not found in profile")
+		return fmt.Errorf("mod %s not found in profile %s", mod, profileName)
+	}
+
+	// Parse with the same semver implementation as the resolver, so that anything accepted here can be resolved
+	if _, err := semver.NewConstraint(constraint); err != nil {
+		l.Error("invalid constraint", slog.Any("error", err))
+		return fmt.Errorf("invalid version constraint %s: %w", constraint, err)
+	}
+
+	setConstraint := func(profile *cli.Profile) {
+		profileMod := profile.Mods[mod]
+		profileMod.Version = constraint
+		profile.Mods[mod] = profileMod
+
+		err := f.ficsitCli.Profiles.Save()
+		if err != nil {
+			l.Error("failed to save profile", slog.Any("error", err))
+		}
+	}
+
+	selectedInstallation := f.GetSelectedInstall()
+	if selectedInstallation == nil || selectedInstallation.Profile != profileName {
+		// Other installations using the profile may be changing it in the queue
+		f.profileLock.Lock()
+		setConstraint(profile)
+		f.profileLock.Unlock()
+		f.EmitModsChange()
+		return nil
+	}
+
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "setModConstraint",
+			Item:         mod,
+			Installation: selectedInstallation.Path,
+		},
+		collapsible: true,
+		apply: func(installation *cli.Installation) error {
+			profile := f.GetProfile(profileName)
+			if profile == nil {
+				return fmt.Errorf("profile %s not found", profileName)
+			}
+			if _, ok := profile.Mods[mod]; !ok {
+				return fmt.Errorf("mod %s not found in profile %s", mod, profileName)
+			}
+			setConstraint(profile)
+			return nil
+		},
+	})
+}
+
+// SetModPinned sets whether the mod is kept at its installed version when checking for and applying updates
+func (f *ficsitCLI) SetModPinned(profileName string, mod string, pinned bool) error {
+	if f.GetProfile(profileName) == nil {
+		return fmt.Errorf("profile %s not found", profileName)
+	}
+
+	if settings.Settings.PinnedMods == nil {
+		settings.Settings.PinnedMods = map[string][]string{}
+	}
+	pinnedMods := settings.Settings.PinnedMods[profileName]
+	isPinned := slices.Contains(pinnedMods, mod)
+	if pinned == isPinned {
+		return nil
+	}
+	if pinned {
+		pinnedMods = append(pinnedMods, mod)
+	} else {
+		pinnedMods = slices.DeleteFunc(pinnedMods, func(m string) bool { return m == mod })
+	}
+	if len(pinnedMods) == 0 {
+		delete(settings.Settings.PinnedMods, profileName)
+	} else {
+		settings.Settings.PinnedMods[profileName] = pinnedMods
+	}
+
+	err := settings.SaveSettings()
+	if err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	f.emitPinnedMods()
+
+	return nil
+}
+
+func (f *ficsitCLI) GetPinnedMods(profileName string) []string {
+	pinnedMods := settings.Settings.PinnedMods[profileName]
+	if pinnedMods == nil {
+		return []string{}
+	}
+	return slices.Clone(pinnedMods)
+}
+
+func (f *ficsitCLI) isModPinned(profileName string, mod string) bool {
+	return slices.Contains(settings.Settings.PinnedMods[profileName], mod)
+}
+
+func (f *ficsitCLI) emitPinnedMods() {
+	selectedInstallation := f.GetSelectedInstall()
+	if selectedInstallation == nil {
+		return
+	}
+	appCommon.EventsEmit("pinnedMods", f.GetPinnedMods(selectedInstallation.Profile))
+}
+
+func (f *ficsitCLI) renamePinnedMods(oldName string, newName string) {
+	pinnedMods, ok := settings.Settings.PinnedMods[oldName]
+	if !ok {
+		return
+	}
+	delete(settings.Settings.PinnedMods, oldName)
+	settings.Settings.PinnedMods[newName] = pinnedMods
+	_ = settings.SaveSettings()
+}
+
+func (f *ficsitCLI) deletePinnedMods(profileName string) {
+	if _, ok := settings.Settings.PinnedMods[profileName]; !ok {
+		return
+	}
+	delete(settings.Settings.PinnedMods, profileName)
+	_ = settings.SaveSettings()
+}
+
+// checkPinnedModsUnchanged fails if any of the pinned mods was installed with a different version than in previousLockfile
+func (f *ficsitCLI) checkPinnedModsUnchanged(installation *cli.Installation, previousLockfile *resolver.LockFile) error {
+	if previousLockfile == nil {
+		return nil
+	}
+	lockfile, err := installation.LockFile(f.ficsitCli)
+	if err != nil {
+		return fmt.Errorf("failed to get lockfile: %w", err)
+	}
+	if lockfile == nil {
+		return nil
+	}
+	for _, mod := range settings.Settings.PinnedMods[installation.Profile] {
+		previous, ok := previousLockfile.Mods[mod]
+		if !ok {
+			continue
+		}
+		current, ok := lockfile.Mods[mod]
+		if !ok || current.Version != previous.Version {
+			return fmt.Errorf("pinned mod %s would change from version %s", mod, previous.Version)
+		}
+	}
+	return nil
+}
+
+type HeldBackReasonType string
+
+const (
+	HeldBackReasonPinned     HeldBackReasonType = "pinned"
+	HeldBackReasonConstraint HeldBackReasonType = "constraint"
+	HeldBackReasonDependency HeldBackReasonType = "dependency"
+	// The newer versions could not be used together with the other mods or the game version
+	HeldBackReasonResolution HeldBackReasonType = "resolution"
+)
+
+var AllHeldBackReasonTypes = []struct {
+	Value  HeldBackReasonType
+	TSName string
+}{
+	{HeldBackReasonPinned, "PINNED"},
+	{HeldBackReasonConstraint, "CONSTRAINT"},
+	{HeldBackReasonDependency, "DEPENDENCY"},
+	{HeldBackReasonResolution, "RESOLUTION"},
+}
+
+type HeldBackReason struct {
+	Type HeldBackReasonType `json:"type"`
+	// The constraint that excludes the latest version, for constraint and dependency
+	Constraint string `json:"constraint,omitempty"`
+	// The mod that depends on this one, for dependency
+	ModReference string `json:"modReference,omitempty"`
+}
+
+type HeldBack struct {
+	ModReference   string           `json:"modReference"`
+	CurrentVersion string           `json:"currentVersion"`
+	LatestVersion  string           `json:"latestVersion"`
+	HeldBack       bool             `json:"heldBack"`
+	Reasons        []HeldBackReason `json:"reasons"`
+}
+
+// WhyHeldBack explains why the mod is not installed with its latest version in the selected installation
+func (f *ficsitCLI) WhyHeldBack(mod string) (*HeldBack, error) {
+	l := slog.With(slog.String("task", "whyHeldBack"), slog.String("mod", mod))
+
+	selectedInstallation := f.GetSelectedInstall()
+	if selectedInstallation == nil {
+		return nil, fmt.Errorf("no installation selected")
+	}
+
+	lockfile, err := selectedInstallation.LockFile(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get lockfile", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get lockfile: %w", err)
+	}
+	if lockfile == nil {
+		lockfile = resolver.NewLockfile()
+	}
+	lockedMod, ok := lockfile.Mods[mod]
+	if !ok {
+		return nil, fmt.Errorf("mod %s is not installed", mod)
+	}
+
+	versions, err := f.ficsitCli.Provider.ModVersionsWithDependencies(context.Background(), mod)
+	if err != nil {
+		l.Error("failed to get mod versions", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get versions of %s: %w", mod, err)
+	}
+
+	result := &HeldBack{
+		ModReference:   mod,
+		CurrentVersion: lockedMod.Version,
+		LatestVersion:  lockedMod.Version,
+		Reasons:        []HeldBackReason{},
+	}
+
+	currentVersion, err := semver.NewVersion(lockedMod.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid installed version %s: %w", lockedMod.Version, err)
+	}
+	latestVersion := currentVersion
+	for _, version := range versions {
+		v, err := semver.NewVersion(version.Version)
+		if err != nil {
+			continue
+		}
+		if v.Compare(latestVersion) > 0 {
+			latestVersion = v
+			result.LatestVersion = version.Version
+		}
+	}
+
+	if latestVersion.Compare(currentVersion) <= 0 {
+		return result, nil
+	}
+	result.HeldBack = true
+
+	if f.isModPinned(selectedInstallation.Profile, mod) {
+		result.Reasons = append(result.Reasons, HeldBackReason{Type: HeldBackReasonPinned})
+	}
+
+	if profile := f.GetProfile(selectedInstallation.Profile); profile != nil {
+		if profileMod, ok := profile.Mods[mod]; ok {
+			constraint, err := semver.NewConstraint(profileMod.Version)
+			if err == nil && !constraint.Contains(latestVersion) {
+				result.Reasons = append(result.Reasons, HeldBackReason{
+					Type:       HeldBackReasonConstraint,
+					Constraint: profileMod.Version,
+				})
+			}
+		}
+	}
+
+	lockedVersions := f.getLockedModVersions(context.Background(), lockfile)
+	for dependent, version := range lockedVersions {
+		for _, dependency := range version.Dependencies {
+			if dependency.ModID != mod {
+				continue
+			}
+			constraint, err := semver.NewConstraint(dependency.Condition)
+			if err == nil && !constraint.Contains(latestVersion) {
+				result.Reasons = append(result.Reasons, HeldBackReason{
+					Type:         HeldBackReasonDependency,
+					Constraint:   dependency.Condition,
+					ModReference: dependent,
+				})
+			}
+		}
+	}
+
+	if len(result.Reasons) == 0 {
+		result.Reasons = append(result.Reasons, HeldBackReason{Type: HeldBackReasonResolution})
+	}
+
+	slices.SortStableFunc(result.Reasons, func(a, b HeldBackReason) int {
+		if a.ModReference < b.ModReference {
+			return -1
+		}
+		if a.ModReference > b.ModReference {
+			return 1
+		}
+		return 0
+	})
+
+	return result, nil
+}
@@ -0,0 +1,280 @@
+package ficsitcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"github.com/spf13/viper"
+)
+
+type ChangeAction string
+
+const (
+	ChangeActionInstallMod    ChangeAction = "installMod"
+	ChangeActionUpdateMods    ChangeAction = "updateMods"
+	ChangeActionSetProfile    ChangeAction = "setProfile"
+	ChangeActionImportProfile ChangeAction = "importProfile"
+)
+
+var AllChangeActions = []struct {
+	Value  ChangeAction
+	TSName string
+}{
+	{ChangeActionInstallMod, "INSTALL_MOD"},
+	{ChangeActionUpdateMods, "UPDATE_MODS"},
+	{ChangeActionSetProfile, "SET_PROFILE"},
+	{ChangeActionImportProfile, "IMPORT_PROFILE"},
+}
+
+type ChangeRequest struct {
+	Action ChangeAction `json:"action"`
+	// Installation to plan the change for, defaults to the selected installation
+	Installation string `json:"installation,omitempty"`
+	// Mod to install for installMod, or mods to update for updateMods
+	Mods []string `json:"mods,omitempty"`
+	// Version constraint for installMod, defaults to the latest version
+	Version string `json:"version,omitempty"`
+	// Profile to switch to for setProfile
+	Profile string `json:"profile,omitempty"`
+	// Exported profile file or share code for importProfile, other sources must be resolved with ResolveProfileSource first
+	File string `json:"file,omitempty"`
+}
+
+type ModChangeType string
+
+const (
+	ModChangeAdded      ModChangeType = "added"
+	ModChangeRemoved    ModChangeType = "removed"
+	ModChangeUpgraded   ModChangeType = "upgraded"
+	ModChangeDowngraded ModChangeType = "downgraded"
+)
+
+type ModChange struct {
+	ModReference string        `json:"modReference"`
+	Type         ModChangeType `json:"type"`
+	FromVersion  string        `json:"fromVersion,omitempty"`
+	ToVersion    string        `json:"toVersion,omitempty"`
+	DownloadSize int64         `json:"downloadSize"`
+	// Whether the mod is in the profile itself, rather than only being a dependency
+	Requested bool `json:"requested"`
+	// Mods that depend on this mod in the resulting lockfile
+	RequiredBy []string `json:"requiredBy"`
+}
+
+type ChangePlan struct {
+	Changes      []ModChange `json:"changes"`
+	DownloadSize int64       `json:"downloadSize"`
+}
+
+// PlanChanges resolves what the installation would look like after the requested change,
+// without changing anything, and returns the difference to what is currently installed.
+func (f *ficsitCLI) PlanChanges(request ChangeRequest) (*ChangePlan, error) {
+	l := slog.With(slog.String("task", "planChanges"), slog.String("action", string(request.Action)))
+
+	selectedInstallation := f.GetSelectedInstall()
+	if request.Installation != "" {
+		selectedInstallation = f.GetInstallation(request.Installation)
+		if selectedInstallation == nil {
+			return nil, fmt.Errorf("installation %s not found", request.Installation)
+		}
+	}
+	if selectedInstallation == nil {
+		return nil, fmt.Errorf("no installation selected")
+	}
+
+	currentLockfile, err := selectedInstallation.LockFile(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get current lockfile", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get current lockfile: %w", err)
+	}
+	if currentLockfile == nil {
+		currentLockfile = resolver.NewLockfile()
+	}
+
+	var targetProfile *cli.Profile
+	var baseLockfile *resolver.LockFile
+
+	switch request.Action {
+	case ChangeActionInstallMod:
+		if len(request.Mods) != 1 {
+			return nil, fmt.Errorf("exactly one mod must be specified")
+		}
+		targetProfile = copyProfile(f.GetProfile(selectedInstallation.Profile))
+		version := request.Version
+		if version == "" {
+			version = ">=0.0.0"
+		}
+		err := targetProfile.AddMod(request.Mods[0], version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add mod: %s@%s: %w", request.Mods[0], version, err)
+		}
+		baseLockfile = currentLockfile
+	case ChangeActionUpdateMods:
+		targetProfile = copyProfile(f.GetProfile(selectedInstallation.Profile))
+		// Same as UpdateMods, so that pinned mods and ignored versions are not shown as updated
+		constraints := f.updateConstraints(targetProfile, currentLockfile, request.Mods, l)
+		toUpdate := make([]string, 0, len(constraints))
+		for modReference, constraint := range constraints {
+			mod := targetProfile.Mods[modReference]
+			mod.Version = constraint
+			targetProfile.Mods[modReference] = mod
+			toUpdate = append(toUpdate, modReference)
+		}
+		baseLockfile = currentLockfile.Clone().Remove(toUpdate...)
+	case ChangeActionSetProfile:
+		profile := f.GetProfile(request.Profile)
+		if profile == nil {
+			return nil, fmt.Errorf("profile %s not found", request.Profile)
+		}
+		targetProfile = copyProfile(profile)
+		// Each profile has its own lockfile in the installation
+		profileInstallation := &cli.Installation{
+			DiskInstance: selectedInstallation.DiskInstance,
+			Path:         selectedInstallation.Path,
+			Profile:      request.Profile,
+		}
+		baseLockfile, err = profileInstallation.LockFile(f.ficsitCli)
+		if err != nil {
+			l.Error("failed to get profile lockfile", slog.Any("error", err))
+			return nil, fmt.Errorf("failed to get lockfile of profile %s: %w", request.Profile, err)
+		}
+	case ChangeActionImportProfile:
+		exportedProfile, err := readExportedProfileSource(request.File)
+		if err != nil {
+			l.Error("failed to read exported profile", slog.Any("error", err))
+			return nil, fmt.Errorf("failed to read exported profile: %w", err)
+		}
+		targetProfile = copyProfile(&exportedProfile.Profile)
+		baseLockfile = &exportedProfile.LockFile
+	default:
+		return nil, fmt.Errorf("unknown action %s", request.Action)
+	}
+
+	gameVersion, err := selectedInstallation.GetGameVersion(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get game version", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get game version: %w", err)
+	}
+
+	platform, err := selectedInstallation.GetPlatform(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get platform", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get platform: %w", err)
+	}
+
+	res := resolver.NewDependencyResolver(f.ficsitCli.Provider, viper.GetString("api-base"))
+
+	targetLockfile, err := targetProfile.Resolve(res, baseLockfile, gameVersion)
+	if err != nil {
+		l.Error("failed to resolve dependencies", slog.Any("error", err))
+		var solvingError resolver.DependencyResolverError
+		if errors.As(err, &solvingError) {
+			return nil, solvingError
+		}
+		return nil, err //nolint:wrapcheck
+	}
+
+	return f.diffLockfiles(currentLockfile, targetLockfile, targetProfile, platform.TargetName), nil
+}
+
+func (f *ficsitCLI) diffLockfiles(currentLockfile *resolver.LockFile, targetLockfile *resolver.LockFile, targetProfile *cli.Profile, targetName string) *ChangePlan {
+	versions := f.getLockedModVersions(context.Background(), targetLockfile)
+	dependents := getDependents(targetLockfile, versions)
+
+	plan := &ChangePlan{
+		Changes: []ModChange{},
+	}
+
+	for modReference, targetMod := range targetLockfile.Mods {
+		change := ModChange{
+			ModReference: modReference,
+			ToVersion:    targetMod.Version,
+			Requested:    targetProfile.IsModEnabled(modReference),
+			RequiredBy:   dependents[modReference],
+		}
+		if change.RequiredBy == nil {
+			change.RequiredBy = []string{}
+		}
+		sort.Strings(change.RequiredBy)
+
+		currentMod, ok := currentLockfile.Mods[modReference]
+		if !ok {
+			change.Type = ModChangeAdded
+		} else {
+			if currentMod.Version == targetMod.Version {
+				continue
+			}
+			change.FromVersion = currentMod.Version
+			change.Type = ModChangeUpgraded
+			if isDowngrade(currentMod.Version, targetMod.Version) {
+				change.Type = ModChangeDowngraded
+			}
+		}
+
+		if version, ok := versions[modReference]; ok {
+			change.DownloadSize = getTargetSize(version, targetName)
+		}
+		plan.DownloadSize += change.DownloadSize
+
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	for modReference, currentMod := range currentLockfile.Mods {
+		if _, ok := targetLockfile.Mods[modReference]; ok {
+			continue
+		}
+		plan.Changes = append(plan.Changes, ModChange{
+			ModReference: modReference,
+			Type:         ModChangeRemoved,
+			FromVersion:  currentMod.Version,
+			RequiredBy:   []string{},
+		})
+	}
+
+	slices.SortFunc(plan.Changes, func(a, b ModChange) int {
+		if a.ModReference < b.ModReference {
+			return -1
+		}
+		if a.ModReference > b.ModReference {
+			return 1
+		}
+		return 0
+	})
+
+	return plan
+}
+
+func isDowngrade(from string, to string) bool {
+	fromVersion, err := semver.NewVersion(from)
+	if err != nil {
+		return false
+	}
+	toVersion, err := semver.NewVersion(to)
+	if err != nil {
+		return false
+	}
+	return toVersion.Compare(fromVersion) < 0
+}
+
+func copyProfile(profile *cli.Profile) *cli.Profile {
+	if profile == nil {
+		return &cli.Profile{Mods: make(map[string]cli.ProfileMod)}
+	}
+	mods := maps.Clone(profile.Mods)
+	if mods == nil {
+		mods = make(map[string]cli.ProfileMod)
+	}
+	return &cli.Profile{
+		Name:            profile.Name,
+		Mods:            mods,
+		RequiredTargets: slices.Clone(profile.RequiredTargets),
+	}
+}
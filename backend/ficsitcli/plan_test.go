@@ -0,0 +1,102 @@
+package ficsitcli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	"github.com/satisfactorymodding/ficsit-cli/cli/provider"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+)
+
+// testProvider only knows the versions of the mods it is given
+type testProvider struct {
+	provider.Provider
+	versions map[string][]resolver.ModVersion
+}
+
+func (p testProvider) ModVersionsWithDependencies(_ context.Context, modID string) ([]resolver.ModVersion, error) {
+	versions, ok := p.versions[modID]
+	if !ok {
+		return nil, fmt.Errorf("mod %s not found", modID)
+	}
+	return versions, nil
+}
+
+func TestDiffLockfiles(t *testing.T) {
+	f := &ficsitCLI{
+		ficsitCli: &cli.GlobalContext{
+			Provider: testProvider{
+				versions: map[string][]resolver.ModVersion{
+					"Added": {
+						{
+							Version:      "1.0.0",
+							Dependencies: []resolver.Dependency{{ModID: "Dependency", Condition: "^2.0.0"}},
+							Targets:      []resolver.Target{{TargetName: resolver.TargetNameWindows, Size: 100}, {TargetName: resolver.TargetNameLinuxServer, Size: 1000}},
+						},
+					},
+					"Dependency": {
+						{Version: "2.1.0", Targets: []resolver.Target{{TargetName: resolver.TargetNameWindows, Size: 20}}},
+					},
+					"Upgraded": {
+						{Version: "1.10.0", Targets: []resolver.Target{{TargetName: resolver.TargetNameWindows, Size: 3}}},
+					},
+				},
+			},
+		},
+	}
+
+	current := &resolver.LockFile{
+		Mods: map[string]resolver.LockedMod{
+			"Dependency": {Version: "2.0.0"},
+			"Upgraded":   {Version: "1.9.0"},
+			"Downgraded": {Version: "2.0.0"},
+			"Prerelease": {Version: "1.0.0"},
+			"Same":       {Version: "1.0.0"},
+			"Removed":    {Version: "1.0.0"},
+		},
+	}
+	target := &resolver.LockFile{
+		Mods: map[string]resolver.LockedMod{
+			"Added":      {Version: "1.0.0"},
+			"Dependency": {Version: "2.1.0"},
+			"Upgraded":   {Version: "1.10.0"},
+			"Downgraded": {Version: "1.5.0"},
+			"Prerelease": {Version: "1.0.0-rc1"},
+			"Same":       {Version: "1.0.0"},
+		},
+	}
+	profile := &cli.Profile{
+		Mods: map[string]cli.ProfileMod{
+			"Added":    {Version: ">=0.0.0", Enabled: true},
+			"Upgraded": {Version: ">=0.0.0", Enabled: false},
+		},
+	}
+
+	plan := f.diffLockfiles(current, target, profile, string(resolver.TargetNameWindows))
+
+	expected := []ModChange{
+		{ModReference: "Added", Type: ModChangeAdded, ToVersion: "1.0.0", DownloadSize: 100, Requested: true, RequiredBy: []string{}},
+		{ModReference: "Dependency", Type: ModChangeUpgraded, FromVersion: "2.0.0", ToVersion: "2.1.0", DownloadSize: 20, RequiredBy: []string{"Added"}},
+		{ModReference: "Downgraded", Type: ModChangeDowngraded, FromVersion: "2.0.0", ToVersion: "1.5.0", RequiredBy: []string{}},
+		{ModReference: "Prerelease", Type: ModChangeDowngraded, FromVersion: "1.0.0", ToVersion: "1.0.0-rc1", RequiredBy: []string{}},
+		{ModReference: "Removed", Type: ModChangeRemoved, FromVersion: "1.0.0", RequiredBy: []string{}},
+		{ModReference: "Upgraded", Type: ModChangeUpgraded, FromVersion: "1.9.0", ToVersion: "1.10.0", DownloadSize: 3, RequiredBy: []string{}},
+	}
+	if !slices.EqualFunc(plan.Changes, expected, func(a, b ModChange) bool {
+		return a.ModReference == b.ModReference &&
+			a.Type == b.Type &&
+			a.FromVersion == b.FromVersion &&
+			a.ToVersion == b.ToVersion &&
+			a.DownloadSize == b.DownloadSize &&
+			a.Requested == b.Requested &&
+			slices.Equal(a.RequiredBy, b.RequiredBy)
+	}) {
+		t.Errorf("got changes %+v, want %+v", plan.Changes, expected)
+	}
+	if plan.DownloadSize != 123 {
+		t.Errorf("got download size %d, want 123", plan.DownloadSize)
+	}
+}
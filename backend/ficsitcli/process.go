@@ -1,23 +1,32 @@
 package ficsitcli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"time"
 
-	"github.com/dustin/go-humanize"
 	"github.com/puzpuzpuz/xsync/v3"
 	"github.com/satisfactorymodding/ficsit-cli/cli"
 	ficsitUtils "github.com/satisfactorymodding/ficsit-cli/utils"
 	resolver "github.com/satisfactorymodding/ficsit-resolver"
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 
 	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/utils"
 )
 
-func (f *ficsitCLI) validateInstall(installation *cli.Installation, progressItem string) error {
+var ErrOperationCancelled = errors.New("operation cancelled")
+
+func (f *ficsitCLI) validateInstall(ctx context.Context, installation *cli.Installation, progressItem string) error {
+	defer f.setProgress(f.progress)
+	return f.validateInstallWithProgress(ctx, installation, progressItem, f.setProgress)
+}
+
+// validateInstallWithProgress installs the mods of the installation's profile,
+// reporting the download and extraction progress to setProgress
+func (f *ficsitCLI) validateInstallWithProgress(ctx context.Context, installation *cli.Installation, progressItem string, setProgress func(*Progress)) error {
 	if !f.isValidInstall(installation.Path) {
 		return fmt.Errorf("invalid installation: %s", installation.Path)
 	}
@@ -27,9 +36,8 @@ func (f *ficsitCLI) validateInstall(installation *cli.Installation, progressItem
 
 	installChannel := make(chan cli.InstallUpdate)
 
-	defer f.setProgress(f.progress)
-
 	type modProgress struct {
+		version          string
 		downloadProgress ficsitUtils.GenericProgress
 		extractProgress  ficsitUtils.GenericProgress
 		downloading      bool
@@ -37,6 +45,14 @@ func (f *ficsitCLI) validateInstall(installation *cli.Installation, progressItem
 	}
 	modProgresses := xsync.NewMapOf[string, modProgress]()
 
+	setPhase := func(p ProgressPhase) {
+		setProgress(&Progress{
+			Item:     progressItem,
+			Phase:    p,
+			Progress: -1,
+		})
+	}
+
 	progressTicker := time.NewTicker(100 * time.Millisecond)
 	done := make(chan bool)
 	defer progressTicker.Stop()
@@ -53,11 +69,13 @@ func (f *ficsitCLI) validateInstall(installation *cli.Installation, progressItem
 			case <-progressTicker.C:
 				downloadBytesProgress := ficsitUtils.GenericProgress{}
 				extractBytesProgress := ficsitUtils.GenericProgress{}
-				downloadModsProgress := ficsitUtils.GenericProgress{}
-				extractModsProgress := ficsitUtils.GenericProgress{}
+				downloadModsProgress := CountProgress{}
+				extractModsProgress := CountProgress{}
 
 				hasDownloading := false
 
+				mods := make([]ModProgress, 0, modProgresses.Size())
+
 				modProgresses.Range(func(key string, value modProgress) bool {
 					if value.downloadProgress.Total != 0 {
 						downloadModsProgress.Total++
@@ -82,9 +100,22 @@ func (f *ficsitCLI) validateInstall(installation *cli.Installation, progressItem
 					extractBytesProgress.Completed += value.extractProgress.Completed
 					extractBytesProgress.Total += value.extractProgress.Total
 
+					mods = append(mods, ModProgress{
+						ModReference: key,
+						Version:      value.version,
+						Download:     CountProgress{Completed: value.downloadProgress.Completed, Total: value.downloadProgress.Total},
+						Extract:      CountProgress{Completed: value.extractProgress.Completed, Total: value.extractProgress.Total},
+						Downloading:  value.downloading,
+						Complete:     value.complete,
+					})
+
 					return true
 				})
 
+				sort.Slice(mods, func(i, j int) bool {
+					return mods[i].ModReference < mods[j].ModReference
+				})
+
 				downloadProgressTracker.Add(downloadBytesProgress.Completed)
 				downloadProgressTracker.Total = downloadBytesProgress.Total
 				extractProgressTracker.Add(extractBytesProgress.Completed)
@@ -92,40 +123,28 @@ func (f *ficsitCLI) validateInstall(installation *cli.Installation, progressItem
 
 				if hasDownloading {
 					if downloadBytesProgress.Total != 0 {
-						eta := downloadProgressTracker.ETA().Round(time.Second)
-						etaText := eta.String()
-						if eta == 0 {
-							etaText = "soon™"
-						}
-						f.setProgress(&Progress{
-							Item: progressItem,
-							Message: fmt.Sprintf(
-								"Downloading %d/%d mods: %s/%s, %s/s, %s",
-								downloadModsProgress.Completed, downloadModsProgress.Total,
-								humanize.Bytes(uint64(downloadBytesProgress.Completed)), humanize.Bytes(uint64(downloadBytesProgress.Total)),
-								humanize.Bytes(uint64(downloadProgressTracker.Speed())),
-								etaText,
-							),
-							Progress: downloadBytesProgress.Percentage(),
+						setProgress(&Progress{
+							Item:        progressItem,
+							Phase:       ProgressPhaseDownloading,
+							Progress:    downloadBytesProgress.Percentage(),
+							Mods:        downloadModsProgress,
+							Bytes:       CountProgress{Completed: downloadBytesProgress.Completed, Total: downloadBytesProgress.Total},
+							Speed:       downloadProgressTracker.Speed(),
+							ETA:         downloadProgressTracker.ETA().Round(time.Second).Seconds(),
+							ModProgress: mods,
 						})
 					}
 				} else {
 					if extractBytesProgress.Total != 0 {
-						eta := extractProgressTracker.ETA().Round(time.Second)
-						etaText := eta.String()
-						if eta == 0 {
-							etaText = "soon™"
-						}
-						f.setProgress(&Progress{
-							Item: progressItem,
-							Message: fmt.Sprintf(
-								"Extracting %d/%d mods: %s/%s, %s/s, %s",
-								extractModsProgress.Completed, extractModsProgress.Total,
-								humanize.Bytes(uint64(extractBytesProgress.Completed)), humanize.Bytes(uint64(extractBytesProgress.Total)),
-								humanize.Bytes(uint64(extractProgressTracker.Speed())),
-								etaText,
-							),
-							Progress: extractBytesProgress.Percentage(),
+						setProgress(&Progress{
+							Item:        progressItem,
+							Phase:       ProgressPhaseExtracting,
+							Progress:    extractBytesProgress.Percentage(),
+							Mods:        extractModsProgress,
+							Bytes:       CountProgress{Completed: extractBytesProgress.Completed, Total: extractBytesProgress.Total},
+							Speed:       extractProgressTracker.Speed(),
+							ETA:         extractProgressTracker.ETA().Round(time.Second).Seconds(),
+							ModProgress: mods,
 						})
 					}
 				}
@@ -144,6 +163,7 @@ func (f *ficsitCLI) validateInstall(installation *cli.Installation, progressItem
 					// Sometimes extract updates are received after the mod is marked as complete.
 					return oldValue, false
 				}
+				oldValue.version = update.Item.Version
 				oldValue.complete = update.Type == cli.InstallUpdateTypeModComplete
 				oldValue.downloading = update.Type == cli.InstallUpdateTypeModDownload
 
@@ -158,8 +178,11 @@ func (f *ficsitCLI) validateInstall(installation *cli.Installation, progressItem
 		}
 	}()
 
-	installErr := installation.Install(f.ficsitCli, installChannel)
+	installErr := f.install(ctx, installation, installChannel, setPhase)
 	if installErr != nil {
+		if errors.Is(installErr, context.Canceled) {
+			return ErrOperationCancelled
+		}
 		var solvingError resolver.DependencyResolverError
 		if errors.As(installErr, &solvingError) {
 			return solvingError
@@ -175,8 +198,8 @@ func (f *ficsitCLI) EmitModsChange() {
 		slog.Error("failed to load lockfile", slog.Any("error", err))
 		return
 	}
-	wailsRuntime.EventsEmit(appCommon.AppContext, "lockfileMods", lockfileMods)
-	wailsRuntime.EventsEmit(appCommon.AppContext, "manifestMods", f.GetSelectedInstallProfileMods())
+	appCommon.EventsEmit("lockfileMods", lockfileMods)
+	appCommon.EventsEmit("manifestMods", f.GetSelectedInstallProfileMods())
 }
 
 func (f *ficsitCLI) EmitGlobals() {
@@ -186,14 +209,14 @@ func (f *ficsitCLI) EmitGlobals() {
 		// We can safely ignore this call.
 		return
 	}
-	wailsRuntime.EventsEmit(appCommon.AppContext, "installations", f.GetInstallations())
-	wailsRuntime.EventsEmit(appCommon.AppContext, "installationsMetadata", f.GetInstallationsMetadata())
-	wailsRuntime.EventsEmit(appCommon.AppContext, "remoteServers", f.GetRemoteInstallations())
+	appCommon.EventsEmit("installations", f.GetInstallations())
+	appCommon.EventsEmit("installationsMetadata", f.GetInstallationsMetadata())
+	appCommon.EventsEmit("remoteServers", f.GetRemoteInstallations())
 	profileNames := make([]string, 0, len(f.ficsitCli.Profiles.Profiles))
 	for k := range f.ficsitCli.Profiles.Profiles {
 		profileNames = append(profileNames, k)
 	}
-	wailsRuntime.EventsEmit(appCommon.AppContext, "profiles", profileNames)
+	appCommon.EventsEmit("profiles", profileNames)
 
 	selectedInstallation := f.GetSelectedInstall()
 
@@ -201,252 +224,197 @@ func (f *ficsitCLI) EmitGlobals() {
 		return
 	}
 
-	wailsRuntime.EventsEmit(appCommon.AppContext, "selectedInstallation", selectedInstallation.Path)
-	wailsRuntime.EventsEmit(appCommon.AppContext, "selectedProfile", selectedInstallation.Profile)
-	wailsRuntime.EventsEmit(appCommon.AppContext, "modsEnabled", !selectedInstallation.Vanilla)
+	appCommon.EventsEmit("selectedInstallation", selectedInstallation.Path)
+	appCommon.EventsEmit("selectedProfile", selectedInstallation.Profile)
+	appCommon.EventsEmit("modsEnabled", !selectedInstallation.Vanilla)
+	appCommon.EventsEmit("pinnedMods", f.GetPinnedMods(selectedInstallation.Profile))
 }
 
 func (f *ficsitCLI) InstallMod(mod string) error {
-	if f.progress != nil {
-		return fmt.Errorf("another operation in progress")
-	}
-
 	selectedInstallation := f.GetSelectedInstall()
 
 	if selectedInstallation == nil {
 		return fmt.Errorf("no installation selected")
 	}
 
-	l := slog.With(
-		slog.String("task", "installMod"),
-		slog.String("mod", mod),
-		slog.String("install", selectedInstallation.Path),
-		slog.String("profile", selectedInstallation.Profile),
-	)
-
-	profileName := selectedInstallation.Profile
-	profile := f.GetProfile(profileName)
-
-	profileErr := profile.AddMod(mod, ">=0.0.0")
-	if profileErr != nil {
-		l.Error("failed to add mod", slog.Any("error", profileErr))
-		return fmt.Errorf("failed to add mod: %s@latest: %w", mod, profileErr)
-	}
-
-	err := f.ficsitCli.Profiles.Save()
-	if err != nil {
-		l.Error("failed to save profile", slog.Any("error", err))
-	}
-
-	f.progress = &Progress{
-		Item:     mod,
-		Message:  "Finding the best version to install",
-		Progress: -1,
-	}
-
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
-
-	installErr := f.validateInstall(selectedInstallation, mod)
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "installMod",
+			Item:         mod,
+			Installation: selectedInstallation.Path,
+		},
+		collapsible: true,
+		apply: func(installation *cli.Installation) error {
+			l := slog.With(
+				slog.String("task", "installMod"),
+				slog.String("mod", mod),
+				slog.String("install", installation.Path),
+				slog.String("profile", installation.Profile),
+			)
+
+			profile := f.GetProfile(installation.Profile)
+
+			profileErr := profile.AddMod(mod, ">=0.0.0")
+			if profileErr != nil {
+				l.Error("failed to add mod", slog.Any("error", profileErr))
+				return fmt.Errorf("failed to add mod: %s@latest: %w", mod, profileErr)
+			}
 
-	if installErr != nil {
-		l.Error("failed to install", slog.Any("error", installErr))
-		return installErr
-	}
+			err := f.ficsitCli.Profiles.Save()
+			if err != nil {
+				l.Error("failed to save profile", slog.Any("error", err))
+			}
 
-	return nil
+			return nil
+		},
+	})
 }
 
 func (f *ficsitCLI) InstallModVersion(mod string, version string) error {
-	if f.progress != nil {
-		return fmt.Errorf("another operation in progress")
-	}
-
 	selectedInstallation := f.GetSelectedInstall()
 
 	if selectedInstallation == nil {
 		return fmt.Errorf("no installation selected")
 	}
 
-	l := slog.With(
-		slog.String("task", "installModVersion"),
-		slog.String("mod", mod),
-		slog.String("version", version),
-		slog.String("install", selectedInstallation.Path),
-		slog.String("profile", selectedInstallation.Profile),
-	)
-
-	profile := f.GetProfile(selectedInstallation.Profile)
-
-	profileErr := profile.AddMod(mod, version)
-	if profileErr != nil {
-		l.Error("failed to add mod", slog.Any("error", profileErr))
-		return fmt.Errorf("failed to add mod: %s@%s: %w", mod, version, profileErr)
-	}
-
-	err := f.ficsitCli.Profiles.Save()
-	if err != nil {
-		l.Error("failed to save profile", slog.Any("error", err))
-	}
-
-	f.progress = &Progress{
-		Item:     mod,
-		Message:  "Finding the best version to install",
-		Progress: -1,
-	}
-
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
-
-	installErr := f.validateInstall(selectedInstallation, mod)
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "installModVersion",
+			Item:         mod,
+			Installation: selectedInstallation.Path,
+		},
+		collapsible: true,
+		apply: func(installation *cli.Installation) error {
+			l := slog.With(
+				slog.String("task", "installModVersion"),
+				slog.String("mod", mod),
+				slog.String("version", version),
+				slog.String("install", installation.Path),
+				slog.String("profile", installation.Profile),
+			)
+
+			profile := f.GetProfile(installation.Profile)
+
+			profileErr := profile.AddMod(mod, version)
+			if profileErr != nil {
+				l.Error("failed to add mod", slog.Any("error", profileErr))
+				return fmt.Errorf("failed to add mod: %s@%s: %w", mod, version, profileErr)
+			}
 
-	if installErr != nil {
-		l.Error("failed to install", slog.Any("error", installErr))
-		return installErr
-	}
+			err := f.ficsitCli.Profiles.Save()
+			if err != nil {
+				l.Error("failed to save profile", slog.Any("error", err))
+			}
 
-	return nil
+			return nil
+		},
+	})
 }
 
 func (f *ficsitCLI) RemoveMod(mod string) error {
-	if f.progress != nil {
-		return fmt.Errorf("another operation in progress")
-	}
-
 	selectedInstallation := f.GetSelectedInstall()
 
 	if selectedInstallation == nil {
 		return fmt.Errorf("no installation selected")
 	}
 
-	l := slog.With(
-		slog.String("task", "removeMod"),
-		slog.String("mod", mod),
-		slog.String("install", selectedInstallation.Path),
-		slog.String("profile", selectedInstallation.Profile),
-	)
-
-	profile := f.GetProfile(selectedInstallation.Profile)
-
-	profile.RemoveMod(mod)
-
-	err := f.ficsitCli.Profiles.Save()
-	if err != nil {
-		l.Error("failed to save profile", slog.Any("error", err))
-	}
-
-	f.progress = &Progress{
-		Item:     mod,
-		Message:  "Checking for mods that are no longer needed",
-		Progress: -1,
-	}
-
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
-
-	installErr := f.validateInstall(selectedInstallation, mod)
-
-	if installErr != nil {
-		l.Error("failed to install", slog.Any("error", installErr))
-		return installErr
-	}
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "removeMod",
+			Item:         mod,
+			Installation: selectedInstallation.Path,
+		},
+		collapsible: true,
+		apply: func(installation *cli.Installation) error {
+			l := slog.With(
+				slog.String("task", "removeMod"),
+				slog.String("mod", mod),
+				slog.String("install", installation.Path),
+				slog.String("profile", installation.Profile),
+			)
+
+			profile := f.GetProfile(installation.Profile)
+
+			profile.RemoveMod(mod)
+
+			err := f.ficsitCli.Profiles.Save()
+			if err != nil {
+				l.Error("failed to save profile", slog.Any("error", err))
+			}
 
-	return nil
+			return nil
+		},
+	})
 }
 
 func (f *ficsitCLI) EnableMod(mod string) error {
-	if f.progress != nil {
-		return fmt.Errorf("another operation in progress")
-	}
-
 	selectedInstallation := f.GetSelectedInstall()
 
 	if selectedInstallation == nil {
 		return fmt.Errorf("no installation selected")
 	}
 
-	l := slog.With(
-		slog.String("task", "enableMod"),
-		slog.String("mod", mod),
-		slog.String("install", selectedInstallation.Path),
-		slog.String("profile", selectedInstallation.Profile),
-	)
-
-	profile := f.GetProfile(selectedInstallation.Profile)
-
-	profile.SetModEnabled(mod, true)
-
-	err := f.ficsitCli.Profiles.Save()
-	if err != nil {
-		l.Error("failed to save profile", slog.Any("error", err))
-	}
-
-	f.progress = &Progress{
-		Item:     mod,
-		Message:  "Finding the best version to install",
-		Progress: -1,
-	}
-
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
-
-	installErr := f.validateInstall(selectedInstallation, mod)
-
-	if installErr != nil {
-		l.Error("failed to install", slog.Any("error", installErr))
-		return installErr
-	}
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "enableMod",
+			Item:         mod,
+			Installation: selectedInstallation.Path,
+		},
+		collapsible: true,
+		apply: func(installation *cli.Installation) error {
+			l := slog.With(
+				slog.String("task", "enableMod"),
+				slog.String("mod", mod),
+				slog.String("install", installation.Path),
+				slog.String("profile", installation.Profile),
+			)
+
+			profile := f.GetProfile(installation.Profile)
+
+			profile.SetModEnabled(mod, true)
+
+			err := f.ficsitCli.Profiles.Save()
+			if err != nil {
+				l.Error("failed to save profile", slog.Any("error", err))
+			}
 
-	return nil
+			return nil
+		},
+	})
 }
 
 func (f *ficsitCLI) DisableMod(mod string) error {
-	if f.progress != nil {
-		return fmt.Errorf("another operation in progress")
-	}
-
 	selectedInstallation := f.GetSelectedInstall()
 
 	if selectedInstallation == nil {
 		return fmt.Errorf("no installation selected")
 	}
 
-	l := slog.With(
-		slog.String("task", "disableMod"),
-		slog.String("mod", mod),
-		slog.String("install", selectedInstallation.Path),
-		slog.String("profile", selectedInstallation.Profile),
-	)
-
-	profile := f.GetProfile(selectedInstallation.Profile)
-
-	profile.SetModEnabled(mod, false)
-
-	err := f.ficsitCli.Profiles.Save()
-	if err != nil {
-		l.Error("failed to save profile", slog.Any("error", err))
-	}
-
-	f.progress = &Progress{
-		Item:     mod,
-		Message:  "Checking for mods that are no longer needed",
-		Progress: -1,
-	}
-
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
-
-	installErr := f.validateInstall(selectedInstallation, mod)
-
-	if installErr != nil {
-		l.Error("failed to install", slog.Any("error", installErr))
-		return installErr
-	}
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "disableMod",
+			Item:         mod,
+			Installation: selectedInstallation.Path,
+		},
+		collapsible: true,
+		apply: func(installation *cli.Installation) error {
+			l := slog.With(
+				slog.String("task", "disableMod"),
+				slog.String("mod", mod),
+				slog.String("install", installation.Path),
+				slog.String("profile", installation.Profile),
+			)
+
+			profile := f.GetProfile(installation.Profile)
+
+			profile.SetModEnabled(mod, false)
+
+			err := f.ficsitCli.Profiles.Save()
+			if err != nil {
+				l.Error("failed to save profile", slog.Any("error", err))
+			}
 
-	return nil
+			return nil
+		},
+	})
 }
@@ -0,0 +1,394 @@
+package ficsitcli
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	ficsitUtils "github.com/satisfactorymodding/ficsit-cli/utils"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"github.com/spf13/viper"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/utils"
+)
+
+// An offline bundle is a zip containing the exported profile, and the ficsit-cli cache files of its locked mods,
+// so that it can be installed without access to ficsit.app
+const (
+	profileBundleExtension   = ".smmbundle"
+	profileBundleProfileName = "profile.smmprofile"
+	profileBundleModsDir     = "mods/"
+)
+
+// Upper bound on the size of a single mod archive in a bundle
+const maxBundledModSize = 2 * 1024 * 1024 * 1024
+
+var zipMagic = []byte("PK\x03\x04")
+
+func modCacheKey(modReference string, version string, target string) string {
+	return modReference + "_" + version + "_" + target + ".zip"
+}
+
+func downloadCacheDir() string {
+	return filepath.Join(viper.GetString("cache-dir"), "downloadCache")
+}
+
+// isProfileBundle checks the file contents rather than the extension, since bundles can be renamed
+func isProfileBundle(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header := make([]byte, len(zipMagic))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, zipMagic)
+}
+
+// ExportCurrentProfileBundle exports the current profile as an offline bundle, asking where to save it
+func (f *ficsitCLI) ExportCurrentProfileBundle() error {
+	l := slog.With(slog.String("task", "exportCurrentProfileBundle"))
+
+	exportedProfile, err := f.MakeCurrentExportedProfile()
+	if err != nil {
+		l.Error("failed to make exported profile", slog.Any("error", err))
+		return fmt.Errorf("failed to export profile: %w", err)
+	}
+
+	defaultFileName := fmt.Sprintf("%s-%s%s", exportedProfile.Profile.Name, time.Now().UTC().Format("2006-01-02-15-04-05"), profileBundleExtension)
+	filename, err := wailsRuntime.SaveFileDialog(appCommon.AppContext, wailsRuntime.SaveDialogOptions{
+		DefaultFilename: defaultFileName,
+		Filters: []wailsRuntime.FileFilter{
+			{
+				Pattern:     "*" + profileBundleExtension,
+				DisplayName: "SMM Offline Bundle (*" + profileBundleExtension + ")",
+			},
+		},
+	})
+	if err != nil {
+		l.Error("failed to open save dialog", slog.Any("error", err))
+		return fmt.Errorf("failed to open save dialog: %w", err)
+	}
+	if filename == "" {
+		// User cancelled
+		return nil
+	}
+
+	return f.writeProfileBundle(filename, exportedProfile)
+}
+
+// ExportCurrentProfileBundleToFile exports the current profile as an offline bundle without asking where to save it
+func (f *ficsitCLI) ExportCurrentProfileBundleToFile(filename string) error {
+	l := slog.With(slog.String("task", "exportCurrentProfileBundleToFile"), slog.String("file", filename))
+
+	exportedProfile, err := f.MakeCurrentExportedProfile()
+	if err != nil {
+		l.Error("failed to make exported profile", slog.Any("error", err))
+		return fmt.Errorf("failed to export profile: %w", err)
+	}
+
+	return f.writeProfileBundle(filename, exportedProfile)
+}
+
+// writeProfileBundle writes the profile and the archives of its locked mods.
+// The archives for the current installation's platform are downloaded if they are not cached,
+// the ones for other platforms are only included if they are already cached.
+func (f *ficsitCLI) writeProfileBundle(filename string, exportedProfile *ExportedProfile) error {
+	l := slog.With(slog.String("task", "writeProfileBundle"), slog.String("file", filename))
+
+	selectedInstallation := f.GetSelectedInstall()
+	if selectedInstallation == nil {
+		return fmt.Errorf("no installation selected")
+	}
+	platform, err := selectedInstallation.GetPlatform(f.ficsitCli)
+	if err != nil {
+		return fmt.Errorf("failed to detect platform: %w", err)
+	}
+
+	exportedProfileJSON, err := utils.JSONMarshal(exportedProfile, 2)
+	if err != nil {
+		l.Error("failed to marshal exported profile", slog.Any("error", err))
+		return fmt.Errorf("failed to marshal exported profile: %w", err)
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		l.Error("failed to create bundle", slog.Any("error", err))
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	err = f.writeProfileBundleContents(zip.NewWriter(file), exportedProfileJSON, exportedProfile, string(platform.TargetName))
+	closeErr := file.Close()
+	if err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write bundle: %w", closeErr)
+	}
+	if err != nil {
+		l.Error("failed to write bundle", slog.Any("error", err))
+		_ = os.Remove(filename)
+		return err
+	}
+	return nil
+}
+
+func (f *ficsitCLI) writeProfileBundleContents(writer *zip.Writer, exportedProfileJSON []byte, exportedProfile *ExportedProfile, platformTarget string) error {
+	l := slog.With(slog.String("task", "writeProfileBundle"))
+
+	profileWriter, err := writer.Create(profileBundleProfileName)
+	if err != nil {
+		return fmt.Errorf("failed to write profile to bundle: %w", err)
+	}
+	if _, err := profileWriter.Write(exportedProfileJSON); err != nil {
+		return fmt.Errorf("failed to write profile to bundle: %w", err)
+	}
+
+	for modReference, lockedMod := range exportedProfile.LockFile.Mods {
+		for target, lockedTarget := range lockedMod.Targets {
+			if lockedTarget.Link == "" {
+				continue
+			}
+			cacheKey := modCacheKey(modReference, lockedMod.Version, target)
+			if target == platformTarget {
+				if err := f.ensureCached(cacheKey, lockedTarget); err != nil {
+					return fmt.Errorf("failed to download %s@%s: %w", modReference, lockedMod.Version, err)
+				}
+			}
+			added, err := addBundledMod(writer, cacheKey, lockedTarget.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to add %s@%s to bundle: %w", modReference, lockedMod.Version, err)
+			}
+			if !added {
+				l.Info("mod not cached for target, skipping", slog.String("mod", modReference), slog.String("target", target))
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+func (f *ficsitCLI) ensureCached(cacheKey string, lockedTarget resolver.LockedModTarget) error {
+	updates := make(chan ficsitUtils.GenericProgress)
+	go func() {
+		for range updates {
+		}
+	}()
+	cached, _, err := f.downloadToCache(context.Background(), cacheKey, lockedTarget.Hash, lockedTarget.Link, updates)
+	if err != nil {
+		return err
+	}
+	return cached.Close() //nolint:wrapcheck
+}
+
+// addBundledMod stores the cache file in the bundle, if it is cached with the right hash.
+// The archives are already compressed, so they are stored as they are.
+func addBundledMod(writer *zip.Writer, cacheKey string, hash string) (bool, error) {
+	location := filepath.Join(downloadCacheDir(), cacheKey)
+	cached, err := isCached(location, hash)
+	if err != nil || !cached {
+		return false, err
+	}
+
+	cacheFile, err := os.Open(location)
+	if err != nil {
+		return false, fmt.Errorf("failed to open cache file: %w", err)
+	}
+	defer cacheFile.Close()
+
+	entryWriter, err := writer.CreateHeader(&zip.FileHeader{
+		Name:   profileBundleModsDir + cacheKey,
+		Method: zip.Store,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create bundle entry: %w", err)
+	}
+	if _, err := io.Copy(entryWriter, cacheFile); err != nil {
+		return false, fmt.Errorf("failed to copy cache file: %w", err)
+	}
+	return true, nil
+}
+
+// readProfileBundle reads the exported profile in the bundle, and counts the mod archives in it
+func readProfileBundle(file string) (*ExportedProfile, int, error) {
+	reader, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer reader.Close()
+
+	var exportedProfile *ExportedProfile
+	bundledMods := 0
+	for _, entry := range reader.File {
+		if strings.HasPrefix(entry.Name, profileBundleModsDir) {
+			bundledMods++
+			continue
+		}
+		if entry.Name != profileBundleProfileName {
+			continue
+		}
+		if entry.UncompressedSize64 > maxExportedProfileSize {
+			return nil, 0, fmt.Errorf("profile is larger than %d bytes", maxExportedProfileSize)
+		}
+		data, err := readZipEntry(entry, maxExportedProfileSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		exportedProfile, err = parseExportedProfile(data)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if exportedProfile == nil {
+		return nil, 0, fmt.Errorf("bundle does not contain %s", profileBundleProfileName)
+	}
+	return exportedProfile, bundledMods, nil
+}
+
+func readZipEntry(entry *zip.File, limit int64) ([]byte, error) {
+	entryReader, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in bundle: %w", entry.Name, err)
+	}
+	defer entryReader.Close()
+	data, err := io.ReadAll(io.LimitReader(entryReader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s in bundle: %w", entry.Name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s in bundle is larger than %d bytes", entry.Name, limit)
+	}
+	return data, nil
+}
+
+// seedCacheFromBundle copies the bundled archives into the download cache, so that installing the profile does not download them.
+// Only archives of mods locked by the profile, and matching their locked hash, are accepted.
+func (f *ficsitCLI) seedCacheFromBundle(file string, exportedProfile *ExportedProfile) error {
+	l := slog.With(slog.String("task", "seedCacheFromBundle"), slog.String("file", file))
+
+	expected := make(map[string]string)
+	for modReference, lockedMod := range exportedProfile.LockFile.Mods {
+		for target, lockedTarget := range lockedMod.Targets {
+			expected[modCacheKey(modReference, lockedMod.Version, target)] = lockedTarget.Hash
+		}
+	}
+
+	reader, err := zip.OpenReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(downloadCacheDir(), 0o777); err != nil {
+		return fmt.Errorf("failed creating download cache: %w", err)
+	}
+
+	seeded := 0
+	for _, entry := range reader.File {
+		cacheKey, ok := strings.CutPrefix(entry.Name, profileBundleModsDir)
+		if !ok {
+			continue
+		}
+		hash, ok := expected[cacheKey]
+		if !ok {
+			l.Warn("skipping archive not in the lockfile", slog.String("entry", entry.Name))
+			continue
+		}
+		if entry.UncompressedSize64 > maxBundledModSize {
+			return fmt.Errorf("%s in bundle is larger than %d bytes", entry.Name, int64(maxBundledModSize))
+		}
+		if err := f.seedCacheFile(entry, cacheKey, hash); err != nil {
+			return err
+		}
+		seeded++
+	}
+
+	l.Info("seeded download cache", slog.Int("files", seeded))
+	return nil
+}
+
+func (f *ficsitCLI) seedCacheFile(entry *zip.File, cacheKey string, hash string) error {
+	location := filepath.Join(downloadCacheDir(), cacheKey)
+	// The cache key comes from the bundle, so it must not be able to point outside the cache
+	rel, err := filepath.Rel(downloadCacheDir(), location)
+	if err != nil || rel != filepath.Base(location) || strings.ContainsAny(cacheKey, `/\`) {
+		return fmt.Errorf("invalid archive name %s in bundle", entry.Name)
+	}
+
+	cacheLock, _ := f.cacheLocks.LoadOrStore(cacheKey, &sync.Mutex{})
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	cached, err := isCached(location, hash)
+	if err != nil {
+		return err
+	}
+	if cached {
+		return nil
+	}
+
+	entryReader, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s in bundle: %w", entry.Name, err)
+	}
+	defer entryReader.Close()
+
+	tmp, err := os.CreateTemp(downloadCacheDir(), cacheKey+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(entryReader, maxBundledModSize))
+	closeErr := tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to extract %s from bundle: %w", entry.Name, err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write cache file: %w", closeErr)
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != hash {
+		return fmt.Errorf("%s in bundle does not match the hash in the lockfile", entry.Name)
+	}
+
+	if err := os.Rename(tmp.Name(), location); err != nil {
+		return fmt.Errorf("failed to move cache file: %w", err)
+	}
+	return nil
+}
+
+func isCached(location string, hash string) (bool, error) {
+	f, err := os.Open(location)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open file: %s: %w", location, err)
+	}
+	defer f.Close()
+
+	if hash == "" {
+		return true, nil
+	}
+
+	existingHash, err := ficsitUtils.SHA256Data(f)
+	if err != nil {
+		return false, fmt.Errorf("could not compute hash for file: %s: %w", location, err)
+	}
+	return existingHash == hash, nil
+}
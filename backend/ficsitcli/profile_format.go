@@ -0,0 +1,193 @@
+package ficsitcli
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+)
+
+// Format version of the exported profiles written by this version.
+// Increase it, and add a migration from the previous version, whenever the format changes.
+const currentProfileFormatVersion = 2
+
+// Profiles exported before the format was versioned have no formatVersion field
+const legacyProfileFormatVersion = 1
+
+//go:embed schema/smmprofile.schema.json
+var exportedProfileSchema string
+
+// profileMigrations upgrade the raw JSON of an exported profile from the version they are keyed by to the next one
+var profileMigrations = map[int]func(raw map[string]interface{}) error{
+	1: migrateProfileV1,
+}
+
+// GetExportedProfileSchema returns the JSON schema of the .smmprofile format
+func (f *ficsitCLI) GetExportedProfileSchema() string {
+	return exportedProfileSchema
+}
+
+// migrateProfileV1 handles profiles whose metadata could be missing or null,
+// and lockfiles written before mods had per-target archives
+func migrateProfileV1(raw map[string]interface{}) error {
+	if metadata, ok := raw["metadata"].(map[string]interface{}); !ok || metadata == nil {
+		raw["metadata"] = map[string]interface{}{"gameVersion": 0}
+	}
+
+	lockfile, ok := raw["lockfile"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	// The first lockfiles were only the map of mods, without a version
+	_, hasMods := lockfile["mods"]
+	_, hasVersion := lockfile["version"]
+	if !hasMods && !hasVersion {
+		lockfile = map[string]interface{}{
+			"mods":    lockfile,
+			"version": int(resolver.InitialLockfileVersion),
+		}
+		raw["lockfile"] = lockfile
+	}
+	mods, ok := lockfile["mods"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, rawMod := range mods {
+		mod, ok := rawMod.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Mods were only available for Windows, with a single archive
+		hash, hasHash := mod["hash"]
+		link, hasLink := mod["link"]
+		if !hasHash && !hasLink {
+			continue
+		}
+		delete(mod, "hash")
+		delete(mod, "link")
+		if _, ok := mod["targets"]; ok {
+			continue
+		}
+		target := map[string]interface{}{}
+		if hasHash {
+			target["hash"] = hash
+		}
+		if hasLink {
+			target["link"] = link
+		}
+		mod["targets"] = map[string]interface{}{string(resolver.TargetNameWindows): target}
+	}
+	return nil
+}
+
+// decodeExportedProfile migrates the exported profile to the current format version,
+// and decodes it rejecting anything that is not part of the format
+func decodeExportedProfile(data []byte) (*ExportedProfile, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, describeJSONError(data, err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	formatVersion := legacyProfileFormatVersion
+	if rawVersion, ok := raw["formatVersion"]; ok {
+		version, ok := rawVersion.(float64)
+		if !ok || version != float64(int(version)) || version < legacyProfileFormatVersion {
+			return nil, fmt.Errorf("field formatVersion must be a positive integer")
+		}
+		formatVersion = int(version)
+	}
+	if formatVersion > currentProfileFormatVersion {
+		return nil, fmt.Errorf("the profile uses format version %d, which is newer than the supported %d, update the mod manager to import it", formatVersion, currentProfileFormatVersion)
+	}
+
+	for version := formatVersion; version < currentProfileFormatVersion; version++ {
+		migrate, ok := profileMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from format version %d", version)
+		}
+		if err := migrate(raw); err != nil {
+			return nil, fmt.Errorf("failed to migrate profile from format version %d: %w", version, err)
+		}
+	}
+	raw["formatVersion"] = currentProfileFormatVersion
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encode migrated profile: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(migrated))
+	decoder.DisallowUnknownFields()
+	var exportedProfile ExportedProfile
+	if err := decoder.Decode(&exportedProfile); err != nil {
+		return nil, describeJSONError(migrated, err)
+	}
+
+	if exportedProfile.Metadata == nil {
+		return nil, fmt.Errorf("missing metadata")
+	}
+	if exportedProfile.LockFile.Version > resolver.CurrentLockfileVersion {
+		return nil, fmt.Errorf("the lockfile uses version %d, which is newer than the supported %d", exportedProfile.LockFile.Version, resolver.CurrentLockfileVersion)
+	}
+
+	return &exportedProfile, nil
+}
+
+// describeJSONError turns the decoding errors into messages that point at the problem in the file
+func describeJSONError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := jsonPosition(data, syntaxErr.Offset)
+		return fmt.Errorf("malformed JSON at line %d, column %d: %w", line, column, err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field == "" {
+			return fmt.Errorf("expected a JSON object, not %s", typeErr.Value)
+		}
+		return fmt.Errorf("field %s must be %s, not %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Errorf("unknown field %s", field)
+	}
+	return fmt.Errorf("invalid profile: %w", err)
+}
+
+// jsonTypeName names the Go type as the JSON type it is decoded from
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	default:
+		return "a number"
+	}
+}
+
+func jsonPosition(data []byte, offset int64) (int, int) {
+	line, column := 1, 1
+	for i := int64(0); i < offset && i < int64(len(data)); i++ {
+		if data[i] == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
@@ -0,0 +1,116 @@
+package ficsitcli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+)
+
+func TestDecodeExportedProfileLegacyLockfile(t *testing.T) {
+	data, err := os.ReadFile("testdata/legacy.smmprofile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exportedProfile, err := decodeExportedProfile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exportedProfile.FormatVersion != currentProfileFormatVersion {
+		t.Errorf("got format version %d, want %d", exportedProfile.FormatVersion, currentProfileFormatVersion)
+	}
+	if exportedProfile.Metadata == nil || exportedProfile.Metadata.GameVersion != 0 {
+		t.Errorf("got metadata %v, want game version 0", exportedProfile.Metadata)
+	}
+	if exportedProfile.Profile.Name != "Factory" || len(exportedProfile.Profile.Mods) != 2 {
+		t.Errorf("got profile %v", exportedProfile.Profile)
+	}
+
+	lockedMod, ok := exportedProfile.LockFile.Mods["SmartFoundations"]
+	if !ok {
+		t.Fatal("SmartFoundations missing from the lockfile")
+	}
+	if lockedMod.Version != "2.9.5" || lockedMod.Dependencies["SML"] != "^3.5.0" {
+		t.Errorf("got locked mod %v", lockedMod)
+	}
+	target, ok := lockedMod.Targets[string(resolver.TargetNameWindows)]
+	if !ok {
+		t.Fatalf("got targets %v, want the Windows target", lockedMod.Targets)
+	}
+	if target.Link != "/v1/version/6nVcbYLBqcnYfm/download" || !strings.HasPrefix(target.Hash, "2b6e2d1c") {
+		t.Errorf("got target %v", target)
+	}
+}
+
+func TestDecodeExportedProfileUnversionedLockfile(t *testing.T) {
+	data := `{
+		"profile": {"name": "Old", "mods": {"SML": {"version": "^3.0.0", "enabled": true}}},
+		"lockfile": {"SML": {"version": "3.0.0", "hash": "abc", "link": "/download", "dependencies": {}}},
+		"metadata": {"gameVersion": 211839}
+	}`
+
+	exportedProfile, err := decodeExportedProfile([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exportedProfile.LockFile.Version != resolver.InitialLockfileVersion {
+		t.Errorf("got lockfile version %d, want %d", exportedProfile.LockFile.Version, resolver.InitialLockfileVersion)
+	}
+	if exportedProfile.LockFile.Mods["SML"].Targets[string(resolver.TargetNameWindows)].Hash != "abc" {
+		t.Errorf("got lockfile %v", exportedProfile.LockFile)
+	}
+}
+
+func TestDecodeExportedProfileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "unknown field",
+			data: `{"formatVersion": 2, "profile": {"name": "A", "mods": {}}, "lockfile": {"mods": {}, "version": 1}, "metadata": {"gameVersion": 1}, "extra": true}`,
+			err:  `unknown field "extra"`,
+		},
+		{
+			name: "unknown lockfile field in the current format",
+			data: `{"formatVersion": 2, "profile": {"name": "A", "mods": {}}, "lockfile": {"mods": {"SML": {"version": "3.0.0", "hash": "abc"}}, "version": 1}, "metadata": {"gameVersion": 1}}`,
+			err:  `unknown field "hash"`,
+		},
+		{
+			name: "newer format",
+			data: `{"formatVersion": 3}`,
+			err:  "newer than the supported",
+		},
+		{
+			name: "invalid format version",
+			data: `{"formatVersion": 1.5}`,
+			err:  "formatVersion must be a positive integer",
+		},
+		{
+			name: "wrong type",
+			data: `{"formatVersion": 2, "profile": {"name": 1}}`,
+			err:  "field profile.name must be a string",
+		},
+		{
+			name: "malformed",
+			data: "{\n\"profile\": }",
+			err:  "malformed JSON at line 2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeExportedProfile([]byte(test.data))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %q, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
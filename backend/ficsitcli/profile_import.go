@@ -0,0 +1,283 @@
+package ficsitcli
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"github.com/spf13/viper"
+)
+
+// Upper bound on the size of an exported profile, whether it is a file, a download or a share string
+const maxExportedProfileSize = 10 * 1024 * 1024
+
+// Upper bound on the number of mods in an exported profile or its lockfile
+const maxExportedProfileMods = 2000
+
+const profileDownloadTimeout = 30 * time.Second
+
+// Profiles downloaded or decoded for importing are kept this long, so that they can be previewed and imported
+const importedProfileRetention = 24 * time.Hour
+
+var importedProfilesDir = "imported-profiles"
+
+var modReferenceRegex = regexp.MustCompile(`^[a-zA-Z0-9_]{1,64}$`)
+
+// Targets that mods can be locked for. The target names are used in cache file names, so nothing else is accepted.
+var knownTargets = []resolver.TargetName{resolver.TargetNameWindows, resolver.TargetNameWindowsServer, resolver.TargetNameLinuxServer}
+
+type ExportedProfileMod struct {
+	ModReference string `json:"modReference"`
+	// Version constraint in the profile, empty for mods that are only dependencies
+	Constraint string `json:"constraint,omitempty"`
+	// Version in the lockfile, empty if the profile was exported before it was installed
+	Version string `json:"version,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// Whether the mod is only in the lockfile, as a dependency of other mods
+	Dependency bool `json:"dependency"`
+}
+
+type ExportedProfilePreview struct {
+	GameVersion int                     `json:"gameVersion"`
+	Name        string                  `json:"name"`
+	Mods        []ExportedProfileMod    `json:"mods"`
+	Metadata    ExportedProfileMetadata `json:"metadata"`
+	// Whether the profile is an offline bundle, and how many mod archives it contains
+	Bundle      bool `json:"bundle"`
+	BundledMods int  `json:"bundledMods"`
+}
+
+// ResolveProfileSource turns the source of a profile to import into a local file that can be previewed
+// with ReadExportedProfileMetadata and imported with ImportProfile.
+// The source can be a path, an HTTP(S) URL, a share code, or the contents of an exported profile as JSON or base64.
+func (f *ficsitCLI) ResolveProfileSource(source string) (string, error) {
+	l := slog.With(slog.String("task", "resolveProfileSource"))
+
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return "", fmt.Errorf("no profile source")
+	}
+
+	cleanupImportedProfiles()
+
+	var data []byte
+	var err error
+	if u, parseErr := url.Parse(source); parseErr == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		l.Info("downloading profile", slog.String("host", u.Host))
+		data, err = downloadExportedProfile(u.String())
+	} else if stat, statErr := os.Stat(source); statErr == nil && !stat.IsDir() {
+		_, err = readExportedProfile(source)
+		if err != nil {
+			return "", err
+		}
+		return source, nil
+	} else {
+		data, err = decodeProfileShareString(source)
+	}
+	if err != nil {
+		l.Error("failed to get profile", slog.Any("error", err))
+		return "", err
+	}
+
+	if _, err := parseExportedProfile(data); err != nil {
+		return "", err
+	}
+
+	return writeImportedProfile(data)
+}
+
+func downloadExportedProfile(profileURL string) ([]byte, error) {
+	client := &http.Client{Timeout: profileDownloadTimeout}
+	resp, err := client.Get(profileURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download profile: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download profile: %s", resp.Status)
+	}
+	if resp.ContentLength > maxExportedProfileSize {
+		return nil, fmt.Errorf("profile is larger than %d bytes", maxExportedProfileSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxExportedProfileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download profile: %w", err)
+	}
+	if len(data) > maxExportedProfileSize {
+		return nil, fmt.Errorf("profile is larger than %d bytes", maxExportedProfileSize)
+	}
+	return data, nil
+}
+
+// decodeProfileShareString accepts a share code, or an exported profile pasted as JSON or encoded as base64
+func decodeProfileShareString(share string) ([]byte, error) {
+	if len(share) > maxExportedProfileSize {
+		return nil, fmt.Errorf("profile is larger than %d bytes", maxExportedProfileSize)
+	}
+	if isProfileShareCode(share) {
+		return decodeProfileShareCode(share)
+	}
+	if strings.HasPrefix(share, "{") {
+		return []byte(share), nil
+	}
+
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		data, err := encoding.DecodeString(share)
+		if err == nil && strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("not a profile file, link or share string")
+}
+
+func writeImportedProfile(data []byte) (string, error) {
+	dir := filepath.Join(viper.GetString("smm-cache-dir"), importedProfilesDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create imported profiles directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, "*.smmprofile")
+	if err != nil {
+		return "", fmt.Errorf("failed to create imported profile file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write imported profile file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+func cleanupImportedProfiles() {
+	dir := filepath.Join(viper.GetString("smm-cache-dir"), importedProfilesDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < importedProfileRetention {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			slog.Warn("failed to remove old imported profile", slog.String("file", entry.Name()), slog.Any("error", err))
+		}
+	}
+}
+
+func parseExportedProfile(data []byte) (*ExportedProfile, error) {
+	if len(data) > maxExportedProfileSize {
+		return nil, fmt.Errorf("profile is larger than %d bytes", maxExportedProfileSize)
+	}
+
+	exportedProfile, err := decodeExportedProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exported profile: %w", err)
+	}
+
+	if err := validateExportedProfile(exportedProfile); err != nil {
+		return nil, fmt.Errorf("invalid exported profile: %w", err)
+	}
+
+	return exportedProfile, nil
+}
+
+// validateExportedProfile checks that everything the import uses is well-formed,
+// since the profile might have been made by hand, or come from an untrusted source
+func validateExportedProfile(exportedProfile *ExportedProfile) error {
+	if exportedProfile.Profile.Mods == nil {
+		return fmt.Errorf("missing profile mods")
+	}
+	if len(exportedProfile.Profile.Mods) > maxExportedProfileMods {
+		return fmt.Errorf("too many mods in profile, at most %d are allowed", maxExportedProfileMods)
+	}
+	for modReference, mod := range exportedProfile.Profile.Mods {
+		if !modReferenceRegex.MatchString(modReference) {
+			return fmt.Errorf("invalid mod reference %q", modReference)
+		}
+		if _, err := semver.NewConstraint(mod.Version); err != nil {
+			return fmt.Errorf("invalid version constraint %q for mod %s: %w", mod.Version, modReference, err)
+		}
+	}
+
+	if len(exportedProfile.LockFile.Mods) > maxExportedProfileMods {
+		return fmt.Errorf("too many mods in lockfile, at most %d are allowed", maxExportedProfileMods)
+	}
+	for modReference, lockedMod := range exportedProfile.LockFile.Mods {
+		if !modReferenceRegex.MatchString(modReference) {
+			return fmt.Errorf("invalid mod reference %q in lockfile", modReference)
+		}
+		if _, err := semver.NewVersion(lockedMod.Version); err != nil {
+			return fmt.Errorf("invalid version %q for mod %s in lockfile: %w", lockedMod.Version, modReference, err)
+		}
+		for target, lockedTarget := range lockedMod.Targets {
+			if !slices.Contains(knownTargets, resolver.TargetName(target)) {
+				return fmt.Errorf("unknown target %q for mod %s", target, modReference)
+			}
+			link, err := url.Parse(lockedTarget.Link)
+			if err != nil || (link.Scheme != "https" && link.Scheme != "http") || link.Host == "" {
+				return fmt.Errorf("invalid download link for mod %s target %s", modReference, target)
+			}
+			if _, err := hex.DecodeString(lockedTarget.Hash); err != nil || lockedTarget.Hash == "" {
+				return fmt.Errorf("invalid hash for mod %s target %s", modReference, target)
+			}
+		}
+	}
+
+	return nil
+}
+
+func makeExportedProfilePreview(exportedProfile *ExportedProfile) *ExportedProfilePreview {
+	preview := &ExportedProfilePreview{
+		Name: exportedProfile.Profile.Name,
+		Mods: []ExportedProfileMod{},
+	}
+	if exportedProfile.Metadata != nil {
+		preview.GameVersion = exportedProfile.Metadata.GameVersion
+		preview.Metadata = *exportedProfile.Metadata
+	}
+
+	for modReference, mod := range exportedProfile.Profile.Mods {
+		preview.Mods = append(preview.Mods, ExportedProfileMod{
+			ModReference: modReference,
+			Constraint:   mod.Version,
+			Version:      exportedProfile.LockFile.Mods[modReference].Version,
+			Enabled:      mod.Enabled,
+		})
+	}
+	for modReference, lockedMod := range exportedProfile.LockFile.Mods {
+		if _, ok := exportedProfile.Profile.Mods[modReference]; ok {
+			continue
+		}
+		preview.Mods = append(preview.Mods, ExportedProfileMod{
+			ModReference: modReference,
+			Version:      lockedMod.Version,
+			Enabled:      true,
+			Dependency:   true,
+		})
+	}
+	sort.Slice(preview.Mods, func(i, j int) bool {
+		if preview.Mods[i].Dependency != preview.Mods[j].Dependency {
+			return !preview.Mods[i].Dependency
+		}
+		return preview.Mods[i].ModReference < preview.Mods[j].ModReference
+	})
+
+	return preview
+}
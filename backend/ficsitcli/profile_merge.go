@@ -0,0 +1,295 @@
+package ficsitcli
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+)
+
+type ProfileModDiffType string
+
+const (
+	ProfileModDiffAdded   ProfileModDiffType = "added"
+	ProfileModDiffRemoved ProfileModDiffType = "removed"
+	ProfileModDiffChanged ProfileModDiffType = "changed"
+)
+
+type ProfileModDiff struct {
+	ModReference string             `json:"modReference"`
+	Type         ProfileModDiffType `json:"type"`
+	// Empty if the mod is not in the profile
+	ConstraintA string `json:"constraintA,omitempty"`
+	ConstraintB string `json:"constraintB,omitempty"`
+	EnabledA    bool   `json:"enabledA"`
+	EnabledB    bool   `json:"enabledB"`
+}
+
+type ProfileDiff struct {
+	// Mods that are only in one of the profiles, or have a different version constraint or enabled state
+	Mods []ProfileModDiff `json:"mods"`
+	// Number of mods that are the same in both profiles
+	Unchanged int `json:"unchanged"`
+}
+
+type MergeStrategy string
+
+const (
+	// Conflicting mods keep the target's version constraint and enabled state
+	MergeStrategyPreferTarget MergeStrategy = "preferTarget"
+	// Conflicting mods take the source's version constraint and enabled state
+	MergeStrategyPreferSource MergeStrategy = "preferSource"
+	// Conflicting mods take the version constraint allowing the highest minimum version,
+	// which is the one contained by the other, and are enabled if they are enabled in either profile.
+	// If neither constraint contains the other, the target's constraint is kept and the conflict is marked unresolved.
+	MergeStrategyUnionHighest MergeStrategy = "unionHighest"
+)
+
+var AllMergeStrategies = []struct {
+	Value  MergeStrategy
+	TSName string
+}{
+	{MergeStrategyPreferTarget, "PREFER_TARGET"},
+	{MergeStrategyPreferSource, "PREFER_SOURCE"},
+	{MergeStrategyUnionHighest, "UNION_HIGHEST"},
+}
+
+// MergeConflict is a mod that is in both profiles with a different version constraint or enabled state
+type MergeConflict struct {
+	ModReference     string `json:"modReference"`
+	TargetConstraint string `json:"targetConstraint"`
+	SourceConstraint string `json:"sourceConstraint"`
+	TargetEnabled    bool   `json:"targetEnabled"`
+	SourceEnabled    bool   `json:"sourceEnabled"`
+	// What the mod is in the merged profile
+	Constraint string `json:"constraint"`
+	Enabled    bool   `json:"enabled"`
+	// Neither version constraint contains the other, so the target's constraint was kept
+	Unresolved bool `json:"unresolved"`
+}
+
+type MergeResult struct {
+	// Mods of the source that were not in the target
+	Added     []string        `json:"added"`
+	Conflicts []MergeConflict `json:"conflicts"`
+	// Number of mods that were the same in both profiles
+	Unchanged int `json:"unchanged"`
+
+	mods map[string]cli.ProfileMod
+}
+
+// DiffProfiles returns the changes from profile a to profile b
+func (f *ficsitCLI) DiffProfiles(a string, b string) (*ProfileDiff, error) {
+	profileA := f.GetProfile(a)
+	if profileA == nil {
+		return nil, fmt.Errorf("profile %s not found", a)
+	}
+	profileB := f.GetProfile(b)
+	if profileB == nil {
+		return nil, fmt.Errorf("profile %s not found", b)
+	}
+
+	diff := &ProfileDiff{
+		Mods: []ProfileModDiff{},
+	}
+	for modReference, modA := range profileA.Mods {
+		modB, ok := profileB.Mods[modReference]
+		switch {
+		case !ok:
+			diff.Mods = append(diff.Mods, ProfileModDiff{
+				ModReference: modReference,
+				Type:         ProfileModDiffRemoved,
+				ConstraintA:  modA.Version,
+				EnabledA:     modA.Enabled,
+			})
+		case modA != modB:
+			diff.Mods = append(diff.Mods, ProfileModDiff{
+				ModReference: modReference,
+				Type:         ProfileModDiffChanged,
+				ConstraintA:  modA.Version,
+				ConstraintB:  modB.Version,
+				EnabledA:     modA.Enabled,
+				EnabledB:     modB.Enabled,
+			})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for modReference, modB := range profileB.Mods {
+		if _, ok := profileA.Mods[modReference]; ok {
+			continue
+		}
+		diff.Mods = append(diff.Mods, ProfileModDiff{
+			ModReference: modReference,
+			Type:         ProfileModDiffAdded,
+			ConstraintB:  modB.Version,
+			EnabledB:     modB.Enabled,
+		})
+	}
+	slices.SortFunc(diff.Mods, func(x, y ProfileModDiff) int { return strings.Compare(x.ModReference, y.ModReference) })
+
+	return diff, nil
+}
+
+// PreviewMergeProfiles returns what MergeProfiles would do, without changing the target profile
+func (f *ficsitCLI) PreviewMergeProfiles(target string, source string, strategy MergeStrategy) (*MergeResult, error) {
+	targetProfile := f.GetProfile(target)
+	if targetProfile == nil {
+		return nil, fmt.Errorf("profile %s not found", target)
+	}
+	sourceProfile := f.GetProfile(source)
+	if sourceProfile == nil {
+		return nil, fmt.Errorf("profile %s not found", source)
+	}
+	return mergeProfileMods(targetProfile.Mods, sourceProfile.Mods, strategy)
+}
+
+// MergeProfiles adds the mods of the source profile to the target profile.
+// Mods that are in both profiles with a different version constraint or enabled state are resolved using the strategy,
+// and reported as conflicts. If the selected installation uses the target profile, the merged profile is installed.
+func (f *ficsitCLI) MergeProfiles(target string, source string, strategy MergeStrategy) (*MergeResult, error) {
+	l := slog.With(slog.String("task", "mergeProfiles"), slog.String("target", target), slog.String("source", source), slog.String("strategy", string(strategy)))
+
+	if target == source {
+		return nil, fmt.Errorf("cannot merge a profile into itself")
+	}
+
+	var result *MergeResult
+	apply := func() error {
+		var err error
+		result, err = f.PreviewMergeProfiles(target, source, strategy)
+		if err != nil {
+			l.Error("failed to merge profiles", slog.Any("error", err))
+			return err
+		}
+		f.GetProfile(target).Mods = result.mods
+
+		err = f.ficsitCli.Profiles.Save()
+		if err != nil {
+			l.Error("failed to save profile", slog.Any("error", err))
+		}
+		return nil
+	}
+
+	selectedInstallation := f.GetSelectedInstall()
+	if selectedInstallation == nil || selectedInstallation.Profile != target {
+		// Other installations using the target profile may be changing it in the queue
+		f.profileLock.Lock()
+		err := apply()
+		f.profileLock.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		f.EmitGlobals()
+		return result, nil
+	}
+
+	// The profile is merged when the operation runs, so that it includes the changes queued before it
+	err := f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "mergeProfiles",
+			Item:         "__merge_profiles__",
+			Installation: selectedInstallation.Path,
+		},
+		apply: func(installation *cli.Installation) error {
+			if installation.Profile != target {
+				return fmt.Errorf("the installation no longer uses profile %s", target)
+			}
+			return apply()
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func mergeProfileMods(targetMods map[string]cli.ProfileMod, sourceMods map[string]cli.ProfileMod, strategy MergeStrategy) (*MergeResult, error) {
+	switch strategy {
+	case MergeStrategyPreferTarget, MergeStrategyPreferSource, MergeStrategyUnionHighest:
+	default:
+		return nil, fmt.Errorf("unknown merge strategy %s", strategy)
+	}
+
+	result := &MergeResult{
+		Added:     []string{},
+		Conflicts: []MergeConflict{},
+		mods:      maps.Clone(targetMods),
+	}
+	if result.mods == nil {
+		result.mods = make(map[string]cli.ProfileMod)
+	}
+
+	for modReference, sourceMod := range sourceMods {
+		targetMod, ok := targetMods[modReference]
+		if !ok {
+			result.mods[modReference] = sourceMod
+			result.Added = append(result.Added, modReference)
+			continue
+		}
+		if targetMod == sourceMod {
+			result.Unchanged++
+			continue
+		}
+
+		merged := targetMod
+		unresolved := false
+		switch strategy {
+		case MergeStrategyPreferTarget:
+		case MergeStrategyPreferSource:
+			merged = sourceMod
+		case MergeStrategyUnionHighest:
+			constraint, ok, err := narrowerConstraint(targetMod.Version, sourceMod.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare version constraints of %s: %w", modReference, err)
+			}
+			if ok {
+				merged.Version = constraint
+			} else {
+				unresolved = true
+			}
+			merged.Enabled = targetMod.Enabled || sourceMod.Enabled
+		}
+		result.mods[modReference] = merged
+
+		result.Conflicts = append(result.Conflicts, MergeConflict{
+			ModReference:     modReference,
+			TargetConstraint: targetMod.Version,
+			SourceConstraint: sourceMod.Version,
+			TargetEnabled:    targetMod.Enabled,
+			SourceEnabled:    sourceMod.Enabled,
+			Constraint:       merged.Version,
+			Enabled:          merged.Enabled,
+			Unresolved:       unresolved,
+		})
+	}
+
+	slices.Sort(result.Added)
+	slices.SortFunc(result.Conflicts, func(x, y MergeConflict) int { return strings.Compare(x.ModReference, y.ModReference) })
+
+	return result, nil
+}
+
+// narrowerConstraint returns the one of the target and source constraints that is contained by the other,
+// which allows the higher minimum version. ok is false if neither constraint contains the other.
+func narrowerConstraint(target string, source string) (string, bool, error) {
+	targetConstraint, err := semver.NewConstraint(target)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid version constraint %s: %w", target, err)
+	}
+	sourceConstraint, err := semver.NewConstraint(source)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid version constraint %s: %w", source, err)
+	}
+	if targetConstraint.Difference(sourceConstraint).IsEmpty() {
+		return target, true, nil
+	}
+	if sourceConstraint.Difference(targetConstraint).IsEmpty() {
+		return source, true, nil
+	}
+	return "", false, nil
+}
@@ -0,0 +1,111 @@
+package ficsitcli
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+)
+
+func TestMergeProfileMods(t *testing.T) {
+	target := map[string]cli.ProfileMod{
+		"Same":      {Version: ">=1.0.0", Enabled: true},
+		"Narrower":  {Version: ">=1.0.0", Enabled: true},
+		"Wider":     {Version: "^1.2.0", Enabled: false},
+		"Disjoint":  {Version: "^1.0.0", Enabled: true},
+		"Overlap":   {Version: ">=1.0.0 <1.5.0", Enabled: true},
+		"OnlyInTgt": {Version: ">=0.0.0", Enabled: true},
+	}
+	source := map[string]cli.ProfileMod{
+		"Same":      {Version: ">=1.0.0", Enabled: true},
+		"Narrower":  {Version: "^1.2.0", Enabled: false},
+		"Wider":     {Version: ">=1.0.0", Enabled: true},
+		"Disjoint":  {Version: "^2.0.0", Enabled: true},
+		"Overlap":   {Version: ">=1.2.0 <2.0.0", Enabled: true},
+		"OnlyInSrc": {Version: "1.0.0", Enabled: false},
+	}
+
+	tests := []struct {
+		strategy   MergeStrategy
+		mods       map[string]cli.ProfileMod
+		unresolved []string
+	}{
+		{
+			strategy: MergeStrategyPreferTarget,
+			mods: map[string]cli.ProfileMod{
+				"Same":      {Version: ">=1.0.0", Enabled: true},
+				"Narrower":  {Version: ">=1.0.0", Enabled: true},
+				"Wider":     {Version: "^1.2.0", Enabled: false},
+				"Disjoint":  {Version: "^1.0.0", Enabled: true},
+				"Overlap":   {Version: ">=1.0.0 <1.5.0", Enabled: true},
+				"OnlyInTgt": {Version: ">=0.0.0", Enabled: true},
+				"OnlyInSrc": {Version: "1.0.0", Enabled: false},
+			},
+		},
+		{
+			strategy: MergeStrategyPreferSource,
+			mods: map[string]cli.ProfileMod{
+				"Same":      {Version: ">=1.0.0", Enabled: true},
+				"Narrower":  {Version: "^1.2.0", Enabled: false},
+				"Wider":     {Version: ">=1.0.0", Enabled: true},
+				"Disjoint":  {Version: "^2.0.0", Enabled: true},
+				"Overlap":   {Version: ">=1.2.0 <2.0.0", Enabled: true},
+				"OnlyInTgt": {Version: ">=0.0.0", Enabled: true},
+				"OnlyInSrc": {Version: "1.0.0", Enabled: false},
+			},
+		},
+		{
+			strategy: MergeStrategyUnionHighest,
+			mods: map[string]cli.ProfileMod{
+				"Same":      {Version: ">=1.0.0", Enabled: true},
+				"Narrower":  {Version: "^1.2.0", Enabled: true},
+				"Wider":     {Version: "^1.2.0", Enabled: true},
+				"Disjoint":  {Version: "^1.0.0", Enabled: true},
+				"Overlap":   {Version: ">=1.0.0 <1.5.0", Enabled: true},
+				"OnlyInTgt": {Version: ">=0.0.0", Enabled: true},
+				"OnlyInSrc": {Version: "1.0.0", Enabled: false},
+			},
+			unresolved: []string{"Disjoint", "Overlap"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.strategy), func(t *testing.T) {
+			result, err := mergeProfileMods(target, source, test.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(result.mods, test.mods) {
+				t.Errorf("got mods %v, want %v", result.mods, test.mods)
+			}
+			if !slices.Equal(result.Added, []string{"OnlyInSrc"}) {
+				t.Errorf("got added %v, want [OnlyInSrc]", result.Added)
+			}
+			if result.Unchanged != 1 {
+				t.Errorf("got %d unchanged, want 1", result.Unchanged)
+			}
+			conflicts := []string{}
+			unresolved := []string{}
+			for _, conflict := range result.Conflicts {
+				conflicts = append(conflicts, conflict.ModReference)
+				if conflict.Unresolved {
+					unresolved = append(unresolved, conflict.ModReference)
+				}
+			}
+			if !slices.Equal(conflicts, []string{"Disjoint", "Narrower", "Overlap", "Wider"}) {
+				t.Errorf("got conflicts %v", conflicts)
+			}
+			if !slices.Equal(unresolved, test.unresolved) && (len(unresolved) != 0 || len(test.unresolved) != 0) {
+				t.Errorf("got unresolved conflicts %v, want %v", unresolved, test.unresolved)
+			}
+			if _, ok := target["OnlyInSrc"]; ok {
+				t.Error("the target mods were changed")
+			}
+		})
+	}
+
+	if _, err := mergeProfileMods(target, source, "unknown"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
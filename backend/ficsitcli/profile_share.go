@@ -0,0 +1,221 @@
+package ficsitcli
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/installfinders/common"
+)
+
+// Share codes start with this prefix, so that they can be told apart from other share strings.
+// The digit is the version of the code encoding, not of the profile format.
+const profileShareCodePrefix = "SMM1-"
+
+// profileShareCodeChecksumSize is the size of the CRC32 of the uncompressed profile, stored before the compressed data
+const profileShareCodeChecksumSize = 4
+
+// profileShareData is the content of a share code. It only keeps what is needed to pick the same mod versions,
+// the download links and hashes of the lockfile would make the code too long to paste in chat.
+// They are looked up again when the imported profile is installed, since installing resolves the profile
+// preferring the versions in the lockfile.
+type profileShareData struct {
+	Name            string                `json:"n"`
+	Mods            map[string]sharedMod  `json:"m"`
+	RequiredTargets []resolver.TargetName `json:"t,omitempty"`
+	GameVersion     int                   `json:"g,omitempty"`
+	InstallType     common.InstallType    `json:"i,omitempty"`
+	SMMVersion      string                `json:"s,omitempty"`
+}
+
+type sharedMod struct {
+	// Version constraint in the profile, empty for dependencies that are only in the lockfile
+	Constraint string `json:"c,omitempty"`
+	// Version in the lockfile, empty if the mod is not locked
+	Version  string `json:"v,omitempty"`
+	Disabled bool   `json:"d,omitempty"`
+}
+
+func makeProfileShareData(exportedProfile *ExportedProfile) *profileShareData {
+	data := &profileShareData{
+		Name:            exportedProfile.Profile.Name,
+		Mods:            make(map[string]sharedMod, len(exportedProfile.LockFile.Mods)),
+		RequiredTargets: exportedProfile.Profile.RequiredTargets,
+	}
+	if exportedProfile.Metadata != nil {
+		data.GameVersion = exportedProfile.Metadata.GameVersion
+		data.InstallType = exportedProfile.Metadata.InstallType
+		data.SMMVersion = exportedProfile.Metadata.SMMVersion
+	}
+	for modReference, mod := range exportedProfile.Profile.Mods {
+		data.Mods[modReference] = sharedMod{
+			Constraint: mod.Version,
+			Disabled:   !mod.Enabled,
+		}
+	}
+	for modReference, lockedMod := range exportedProfile.LockFile.Mods {
+		mod := data.Mods[modReference]
+		mod.Version = lockedMod.Version
+		data.Mods[modReference] = mod
+	}
+	return data
+}
+
+// exportedProfile rebuilds the exported profile, with a lockfile that only has the locked versions
+func (data *profileShareData) exportedProfile() *ExportedProfile {
+	exportedProfile := &ExportedProfile{
+		FormatVersion: currentProfileFormatVersion,
+		Profile: cli.Profile{
+			Name:            data.Name,
+			Mods:            make(map[string]cli.ProfileMod),
+			RequiredTargets: data.RequiredTargets,
+		},
+		LockFile: *resolver.NewLockfile(),
+		Metadata: &ExportedProfileMetadata{
+			GameVersion: data.GameVersion,
+			InstallType: data.InstallType,
+			SMMVersion:  data.SMMVersion,
+		},
+	}
+	for modReference, mod := range data.Mods {
+		if mod.Constraint != "" {
+			exportedProfile.Profile.Mods[modReference] = cli.ProfileMod{
+				Version: mod.Constraint,
+				Enabled: !mod.Disabled,
+			}
+		}
+		if mod.Version != "" {
+			exportedProfile.LockFile.Mods[modReference] = resolver.LockedMod{
+				Version: mod.Version,
+			}
+		}
+	}
+	exportedProfile.Metadata.SMLVersion = exportedProfile.LockFile.Mods["SML"].Version
+	return exportedProfile
+}
+
+// ExportCurrentProfileCode exports the current profile as a share code, that can be pasted in chat and imported with ImportProfile
+func (f *ficsitCLI) ExportCurrentProfileCode() (string, error) {
+	l := slog.With(slog.String("task", "exportCurrentProfileCode"))
+
+	exportedProfile, err := f.MakeCurrentExportedProfile()
+	if err != nil {
+		l.Error("failed to make exported profile", slog.Any("error", err))
+		return "", fmt.Errorf("failed to export profile: %w", err)
+	}
+
+	code, err := encodeProfileShareCode(exportedProfile)
+	if err != nil {
+		l.Error("failed to encode share code", slog.Any("error", err))
+		return "", err
+	}
+	return code, nil
+}
+
+// ResolveProfileShareCode turns the share code into a local file that can be previewed and imported like ResolveProfileSource.
+// Unlike ResolveProfileSource, it accepts nothing but share codes, so it never reads files or downloads anything.
+func (f *ficsitCLI) ResolveProfileShareCode(code string) (string, error) {
+	if !isProfileShareCode(code) {
+		return "", fmt.Errorf("not a profile share code")
+	}
+
+	cleanupImportedProfiles()
+
+	data, err := decodeProfileShareCode(code)
+	if err != nil {
+		slog.Error("failed to decode share code", slog.Any("error", err))
+		return "", err
+	}
+	if _, err := parseExportedProfile(data); err != nil {
+		return "", err
+	}
+	return writeImportedProfile(data)
+}
+
+func isProfileShareCode(source string) bool {
+	source = strings.TrimSpace(source)
+	return strings.HasPrefix(source, profileShareCodePrefix)
+}
+
+// encodeProfileShareCode compresses the JSON of the share data, and prefixes it with its checksum
+func encodeProfileShareCode(exportedProfile *ExportedProfile) (string, error) {
+	data, err := json.Marshal(makeProfileShareData(exportedProfile))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal exported profile: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(data)))
+
+	writer, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", fmt.Errorf("failed to create compressor: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return "", fmt.Errorf("failed to compress profile: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress profile: %w", err)
+	}
+
+	return profileShareCodePrefix + base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeProfileShareCode returns the exported profile JSON from the share code.
+// Its lockfile only has the mod versions, the download links and hashes are resolved when installing.
+// Whitespace is ignored, since chat clients can wrap long codes.
+func decodeProfileShareCode(code string) ([]byte, error) {
+	code = strings.Join(strings.Fields(code), "")
+	encoded, ok := strings.CutPrefix(code, profileShareCodePrefix)
+	if !ok {
+		return nil, fmt.Errorf("not a profile share code")
+	}
+	if base64.RawURLEncoding.DecodedLen(len(encoded)) > maxExportedProfileSize {
+		return nil, fmt.Errorf("share code is larger than %d bytes", maxExportedProfileSize)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, fmt.Errorf("share code is malformed, it may have been copied incompletely: %w", err)
+	}
+	if len(raw) <= profileShareCodeChecksumSize {
+		return nil, fmt.Errorf("share code is too short, it may have been copied incompletely")
+	}
+	checksum := binary.BigEndian.Uint32(raw[:profileShareCodeChecksumSize])
+
+	reader := flate.NewReader(bytes.NewReader(raw[profileShareCodeChecksumSize:]))
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, maxExportedProfileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("share code is malformed, it may have been copied incompletely: %w", err)
+	}
+	if len(data) > maxExportedProfileSize {
+		return nil, fmt.Errorf("profile is larger than %d bytes", maxExportedProfileSize)
+	}
+	if crc32.ChecksumIEEE(data) != checksum {
+		return nil, fmt.Errorf("share code checksum does not match, it may have been copied incorrectly")
+	}
+
+	var shareData profileShareData
+	if err := json.Unmarshal(data, &shareData); err != nil {
+		return nil, fmt.Errorf("share code is malformed: %w", err)
+	}
+	if shareData.Mods == nil {
+		return nil, fmt.Errorf("share code is malformed: missing mods")
+	}
+	exportedProfile, err := json.Marshal(shareData.exportedProfile())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal exported profile: %w", err)
+	}
+	return exportedProfile, nil
+}
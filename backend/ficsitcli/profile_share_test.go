@@ -0,0 +1,129 @@
+package ficsitcli
+
+import (
+	"encoding/base64"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/installfinders/common"
+)
+
+func testExportedProfile() *ExportedProfile {
+	return &ExportedProfile{
+		FormatVersion: currentProfileFormatVersion,
+		Profile: cli.Profile{
+			Name: "Factory",
+			Mods: map[string]cli.ProfileMod{
+				"SmartFoundations": {Version: ">=2.9.0", Enabled: true},
+				"RefinedPower":     {Version: "^3.2.0", Enabled: false},
+			},
+			RequiredTargets: []resolver.TargetName{resolver.TargetNameWindows},
+		},
+		LockFile: resolver.LockFile{
+			Version: resolver.CurrentLockfileVersion,
+			Mods: map[string]resolver.LockedMod{
+				"SML": {
+					Version: "3.6.1",
+					Targets: map[string]resolver.LockedModTarget{
+						"Windows": {Hash: "abc", Link: "/download/sml"},
+					},
+				},
+				"SmartFoundations": {
+					Version:      "2.9.5",
+					Dependencies: map[string]string{"SML": "^3.6.0"},
+				},
+			},
+		},
+		Metadata: &ExportedProfileMetadata{
+			GameVersion: 264901,
+			SMMVersion:  "3.0.0",
+			InstallType: common.InstallTypeWindowsClient,
+		},
+	}
+}
+
+func TestProfileShareCodeRoundTrip(t *testing.T) {
+	code, err := encodeProfileShareCode(testExportedProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isProfileShareCode(code) {
+		t.Fatalf("%s is not recognized as a share code", code)
+	}
+
+	// Chat clients can wrap the code
+	wrapped := code[:20] + "\n" + code[20:]
+	data, err := decodeProfileShareCode(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeExportedProfile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := testExportedProfile()
+	if decoded.Profile.Name != expected.Profile.Name || !maps.Equal(decoded.Profile.Mods, expected.Profile.Mods) {
+		t.Errorf("got profile %v, want %v", decoded.Profile, expected.Profile)
+	}
+	if len(decoded.Profile.RequiredTargets) != 1 || decoded.Profile.RequiredTargets[0] != resolver.TargetNameWindows {
+		t.Errorf("got required targets %v", decoded.Profile.RequiredTargets)
+	}
+	if len(decoded.LockFile.Mods) != len(expected.LockFile.Mods) {
+		t.Errorf("got %d locked mods, want %d", len(decoded.LockFile.Mods), len(expected.LockFile.Mods))
+	}
+	for modReference, lockedMod := range expected.LockFile.Mods {
+		decodedMod := decoded.LockFile.Mods[modReference]
+		if decodedMod.Version != lockedMod.Version {
+			t.Errorf("got version %s of %s, want %s", decodedMod.Version, modReference, lockedMod.Version)
+		}
+		// Only the versions are shared, the rest is resolved when installing
+		if len(decodedMod.Targets) != 0 || len(decodedMod.Dependencies) != 0 {
+			t.Errorf("got locked mod %v, want only the version", decodedMod)
+		}
+	}
+	if *decoded.Metadata != (ExportedProfileMetadata{GameVersion: 264901, SMMVersion: "3.0.0", InstallType: common.InstallTypeWindowsClient, SMLVersion: "3.6.1"}) {
+		t.Errorf("got metadata %v", *decoded.Metadata)
+	}
+}
+
+func TestProfileShareCodeErrors(t *testing.T) {
+	code, err := encodeProfileShareCode(testExportedProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(code, profileShareCodePrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[0] ^= 0xff
+	badChecksum := profileShareCodePrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name string
+		code string
+		err  string
+	}{
+		{name: "checksum", code: badChecksum, err: "checksum does not match"},
+		{name: "truncated", code: code[:len(code)/2], err: "copied incompletely"},
+		{name: "too short", code: profileShareCodePrefix + "AAA", err: "too short"},
+		{name: "not base64", code: profileShareCodePrefix + "!!!!", err: "malformed"},
+		{name: "prefix", code: "SMM0-" + strings.TrimPrefix(code, profileShareCodePrefix), err: "not a profile share code"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeProfileShareCode(test.code)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %q, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package ficsitcli
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
@@ -9,10 +8,13 @@ import (
 	"time"
 
 	"github.com/satisfactorymodding/ficsit-cli/cli"
+	ficsitcache "github.com/satisfactorymodding/ficsit-cli/cli/cache"
 	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"github.com/spf13/viper"
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 
 	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/installfinders/common"
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/utils"
 )
 
@@ -29,37 +31,29 @@ func (f *ficsitCLI) SetProfile(profile string) error {
 		return nil
 	}
 
-	err := selectedInstallation.SetProfile(f.ficsitCli, profile)
-	if err != nil {
-		l.Error("failed to set profile", slog.Any("error", err))
-		return fmt.Errorf("failed to set profile: %w", err)
-	}
-
-	err = f.ficsitCli.Installations.Save()
-	if err != nil {
-		l.Error("failed to save installations", slog.Any("error", err))
-	}
-
-	f.EmitGlobals()
-
-	f.progress = &Progress{
-		Item:     "__select_profile__",
-		Message:  "Validating install",
-		Progress: -1,
-	}
-
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "setProfile",
+			Item:         "__select_profile__",
+			Installation: selectedInstallation.Path,
+		},
+		apply: func(installation *cli.Installation) error {
+			err := installation.SetProfile(f.ficsitCli, profile)
+			if err != nil {
+				l.Error("failed to set profile", slog.Any("error", err))
+				return fmt.Errorf("failed to set profile: %w", err)
+			}
 
-	installErr := f.validateInstall(selectedInstallation, "__select_profile__")
+			err = f.ficsitCli.Installations.Save()
+			if err != nil {
+				l.Error("failed to save installations", slog.Any("error", err))
+			}
 
-	if installErr != nil {
-		l.Error("failed to validate installation", slog.Any("error", installErr))
-		return installErr
-	}
+			f.EmitGlobals()
 
-	return nil
+			return nil
+		},
+	})
 }
 
 func (f *ficsitCLI) GetSelectedProfile() *string {
@@ -138,6 +132,8 @@ func (f *ficsitCLI) RenameProfile(oldName string, newName string) error {
 		return fmt.Errorf("failed to rename profile: %s -> %s: %w", oldName, newName, err)
 	}
 
+	f.renamePinnedMods(oldName, newName)
+
 	err = f.ficsitCli.Profiles.Save()
 	if err != nil {
 		l.Error("failed to save profile", slog.Any("error", err))
@@ -171,6 +167,8 @@ func (f *ficsitCLI) DeleteProfile(name string) error {
 		return fmt.Errorf("failed to delete profile: %s: %w", name, err)
 	}
 
+	f.deletePinnedMods(name)
+
 	err = f.ficsitCli.Profiles.Save()
 	if err != nil {
 		l.Error("failed to save profile", slog.Any("error", err))
@@ -187,14 +185,21 @@ func (f *ficsitCLI) DeleteProfile(name string) error {
 	return nil
 }
 
+// ExportedProfile is the contents of a .smmprofile file, described by schema/smmprofile.schema.json
 type ExportedProfile struct {
-	Profile  cli.Profile              `json:"profile"`
-	LockFile resolver.LockFile        `json:"lockfile"`
-	Metadata *ExportedProfileMetadata `json:"metadata"`
+	FormatVersion int                      `json:"formatVersion"`
+	Profile       cli.Profile              `json:"profile"`
+	LockFile      resolver.LockFile        `json:"lockfile"`
+	Metadata      *ExportedProfileMetadata `json:"metadata"`
 }
 
 type ExportedProfileMetadata struct {
 	GameVersion int `json:"gameVersion"`
+	// The fields below are missing from profiles exported before format version 2
+	SMMVersion  string             `json:"smmVersion,omitempty"`
+	ExportedAt  *time.Time         `json:"exportedAt,omitempty"`
+	InstallType common.InstallType `json:"installType,omitempty"`
+	SMLVersion  string             `json:"smlVersion,omitempty"`
 }
 
 func (f *ficsitCLI) MakeCurrentExportedProfile() (*ExportedProfile, error) {
@@ -224,23 +229,27 @@ func (f *ficsitCLI) MakeCurrentExportedProfile() (*ExportedProfile, error) {
 		return nil, fmt.Errorf("failed to get lockfile: %w", err)
 	}
 
-	installMetadata, ok := f.installationMetadata.Load(selectedInstallation.Path)
-	var gameVersion int
-	if ok && installMetadata.Info != nil {
-		gameVersion = installMetadata.Info.Version
+	if lockfile == nil {
+		lockfile = resolver.NewLockfile()
 	}
+
+	exportedAt := time.Now().UTC()
 	metadata := &ExportedProfileMetadata{
-		GameVersion: gameVersion,
+		SMMVersion: viper.GetString("version"),
+		ExportedAt: &exportedAt,
+		SMLVersion: lockfile.Mods["SML"].Version,
 	}
-
-	if lockfile == nil {
-		lockfile = resolver.NewLockfile()
+	installMetadata, ok := f.installationMetadata.Load(selectedInstallation.Path)
+	if ok && installMetadata.Info != nil {
+		metadata.GameVersion = installMetadata.Info.Version
+		metadata.InstallType = installMetadata.Info.Type
 	}
 
 	return &ExportedProfile{
-		Profile:  *profile,
-		LockFile: *lockfile,
-		Metadata: metadata,
+		FormatVersion: currentProfileFormatVersion,
+		Profile:       *profile,
+		LockFile:      *lockfile,
+		Metadata:      metadata,
 	}, nil
 }
 
@@ -272,6 +281,25 @@ func (f *ficsitCLI) ExportCurrentProfile() error {
 		return nil
 	}
 
+	return writeExportedProfile(filename, exportedProfile)
+}
+
+// ExportCurrentProfileToFile exports the current profile without asking where to save it
+func (f *ficsitCLI) ExportCurrentProfileToFile(filename string) error {
+	l := slog.With(slog.String("task", "exportCurrentProfileToFile"), slog.String("file", filename))
+
+	exportedProfile, err := f.MakeCurrentExportedProfile()
+	if err != nil {
+		l.Error("failed to make exported profile", slog.Any("error", err))
+		return fmt.Errorf("failed to export profile: %w", err)
+	}
+
+	return writeExportedProfile(filename, exportedProfile)
+}
+
+func writeExportedProfile(filename string, exportedProfile *ExportedProfile) error {
+	l := slog.With(slog.String("task", "writeExportedProfile"), slog.String("file", filename))
+
 	exportedProfileJSON, err := utils.JSONMarshal(exportedProfile, 2)
 	if err != nil {
 		l.Error("failed to marshal exported profile", slog.Any("error", err))
@@ -286,25 +314,63 @@ func (f *ficsitCLI) ExportCurrentProfile() error {
 	return nil
 }
 
-func (f *ficsitCLI) ReadExportedProfileMetadata(file string) (*ExportedProfileMetadata, error) {
-	l := slog.With(slog.String("task", "readExportedProfileMetadata"), slog.String("file", file))
+func readExportedProfile(file string) (*ExportedProfile, error) {
+	if isProfileBundle(file) {
+		exportedProfile, _, err := readProfileBundle(file)
+		return exportedProfile, err
+	}
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exported profile: %w", err)
+	}
+	if stat.Size() > maxExportedProfileSize {
+		return nil, fmt.Errorf("profile is larger than %d bytes", maxExportedProfileSize)
+	}
 
 	fileBytes, err := os.ReadFile(file)
 	if err != nil {
-		l.Error("failed to read exported profile", slog.Any("error", err))
 		return nil, fmt.Errorf("failed to read exported profile: %w", err)
 	}
 
-	var exportedProfile ExportedProfile
-	err = json.Unmarshal(fileBytes, &exportedProfile)
+	return parseExportedProfile(fileBytes)
+}
+
+// readExportedProfileSource reads the exported profile from a file, or from a share code
+func readExportedProfileSource(source string) (*ExportedProfile, error) {
+	if isProfileShareCode(source) {
+		data, err := decodeProfileShareCode(source)
+		if err != nil {
+			return nil, err
+		}
+		return parseExportedProfile(data)
+	}
+	return readExportedProfile(source)
+}
+
+// ReadExportedProfileMetadata reads the exported profile for previewing it before importing
+func (f *ficsitCLI) ReadExportedProfileMetadata(file string) (*ExportedProfilePreview, error) {
+	l := slog.With(slog.String("task", "readExportedProfileMetadata"), slog.String("file", file))
+
+	exportedProfile, err := readExportedProfileSource(file)
 	if err != nil {
-		l.Error("failed to unmarshal exported profile", slog.Any("error", err))
-		return nil, fmt.Errorf("failed to parse exported profile: %w", err)
+		l.Error("failed to read exported profile", slog.Any("error", err))
+		return nil, err
 	}
 
-	return exportedProfile.Metadata, nil
+	preview := makeExportedProfilePreview(exportedProfile)
+	if isProfileBundle(file) {
+		_, preview.BundledMods, err = readProfileBundle(file)
+		if err != nil {
+			return nil, err
+		}
+		preview.Bundle = true
+	}
+	return preview, nil
 }
 
+// ImportProfile adds the exported profile as a new profile. The file can also be a share code.
+// The mods of offline bundles are added to the download cache, so they are installed without downloading them.
 func (f *ficsitCLI) ImportProfile(name string, file string) error {
 	l := slog.With(slog.String("task", "importProfile"), slog.String("name", name), slog.String("file", file))
 
@@ -315,63 +381,60 @@ func (f *ficsitCLI) ImportProfile(name string, file string) error {
 		return fmt.Errorf("no installation selected")
 	}
 
-	profileData, err := os.ReadFile(file)
+	exportedProfile, err := readExportedProfileSource(file)
 	if err != nil {
 		l.Error("failed to read exported profile", slog.Any("error", err))
 		return fmt.Errorf("failed to read profile file: %w", err)
 	}
 
-	var exportedProfile ExportedProfile
-	err = json.Unmarshal(profileData, &exportedProfile)
-	if err != nil {
-		l.Error("failed to unmarshal exported profile", slog.Any("error", err))
-		return fmt.Errorf("failed to read profile file: %w", err)
-	}
-
-	profile, err := f.ficsitCli.Profiles.AddProfile(name)
-	if err != nil {
-		l.Error("failed to add profile", slog.Any("error", err))
-		return fmt.Errorf("failed to add imported profile: %w", err)
-	}
-
-	profile.Mods = exportedProfile.Profile.Mods
-
-	currentProfile := selectedInstallation.Profile
-
-	_ = selectedInstallation.SetProfile(f.ficsitCli, name)
-
-	err = selectedInstallation.WriteLockFile(f.ficsitCli, &exportedProfile.LockFile)
-	if err != nil {
-		_ = selectedInstallation.SetProfile(f.ficsitCli, currentProfile)
-		_ = f.ficsitCli.Profiles.DeleteProfile(name)
-		l.Error("failed to write lockfile", slog.Any("error", err))
-		return fmt.Errorf("failed to write profile: %w", err)
+	bundle := isProfileBundle(file)
+	if bundle {
+		err = f.seedCacheFromBundle(file, exportedProfile)
+		if err != nil {
+			l.Error("failed to seed cache from bundle", slog.Any("error", err))
+			return fmt.Errorf("failed to read bundled mods: %w", err)
+		}
 	}
 
-	f.EmitGlobals()
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "importProfile",
+			Item:         "__import_profile__",
+			Installation: selectedInstallation.Path,
+		},
+		apply: func(installation *cli.Installation) error {
+			if bundle {
+				// Make the seeded archives available to the offline mode.
+				// The cache is reloaded in the queue, so that no install adds to it at the same time.
+				if _, err := ficsitcache.LoadCache(); err != nil {
+					l.Warn("failed to reload cache", slog.Any("error", err))
+				}
+			}
 
-	f.progress = &Progress{
-		Item:     "__import_profile__",
-		Message:  "Validating install",
-		Progress: -1,
-	}
+			profile, err := f.ficsitCli.Profiles.AddProfile(name)
+			if err != nil {
+				l.Error("failed to add profile", slog.Any("error", err))
+				return fmt.Errorf("failed to add imported profile: %w", err)
+			}
 
-	f.setProgress(f.progress)
+			profile.Mods = exportedProfile.Profile.Mods
 
-	defer f.setProgress(nil)
+			_ = installation.SetProfile(f.ficsitCli, name)
 
-	installErr := f.validateInstall(selectedInstallation, "__import_profile__")
+			err = installation.WriteLockFile(f.ficsitCli, &exportedProfile.LockFile)
+			if err != nil {
+				l.Error("failed to write lockfile", slog.Any("error", err))
+				return fmt.Errorf("failed to write profile: %w", err)
+			}
 
-	if installErr != nil {
-		_ = f.ficsitCli.Profiles.DeleteProfile(name)
-		l.Error("failed to validate installation", slog.Any("error", installErr))
-		return installErr
-	}
+			err = f.ficsitCli.Profiles.Save()
+			if err != nil {
+				l.Error("failed to save profile", slog.Any("error", err))
+			}
 
-	err = f.ficsitCli.Profiles.Save()
-	if err != nil {
-		l.Error("failed to save profile", slog.Any("error", err))
-	}
+			f.EmitGlobals()
 
-	return nil
+			return nil
+		},
+	})
 }
@@ -0,0 +1,363 @@
+package ficsitcli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+)
+
+type OperationState string
+
+const (
+	OperationStatePending   OperationState = "pending"
+	OperationStateRunning   OperationState = "running"
+	OperationStateCompleted OperationState = "completed"
+	OperationStateFailed    OperationState = "failed"
+)
+
+type Operation struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Item         string         `json:"item"`
+	Installation string         `json:"installation"`
+	State        OperationState `json:"state"`
+	Error        string         `json:"error,omitempty"`
+	RolledBack   bool           `json:"rolledBack"`
+	// Explanation of the failure, if the mods could not be resolved
+	Conflict *ConflictReport `json:"conflict,omitempty"`
+}
+
+type queuedOperation struct {
+	Operation
+
+	// Collapsible operations that are queued one after another for the same installation
+	// are applied together and share a single validateInstall pass
+	collapsible bool
+	// apply makes the changes of this operation, before the installation gets validated
+	apply func(installation *cli.Installation) error
+	// verify, if set, checks the installation after it was validated. If it fails, the operation is rolled back.
+	verify func(installation *cli.Installation) error
+	// run replaces apply for operations that handle the installations themselves, such as those affecting multiple installations.
+	// These are never collapsed, and are responsible for their own validation and rollback.
+	run func(ctx context.Context) error
+
+	done chan error
+}
+
+type operationQueue struct {
+	lock       sync.Mutex
+	operations []*queuedOperation
+	nextID     int
+	wake       chan bool
+	running    []string
+	cancel     context.CancelFunc
+}
+
+func newOperationQueue() *operationQueue {
+	return &operationQueue{
+		wake: make(chan bool, 1),
+	}
+}
+
+func (f *ficsitCLI) queueOperation(op *queuedOperation) error {
+	f.queue.lock.Lock()
+	f.queue.nextID++
+	op.ID = strconv.Itoa(f.queue.nextID)
+	op.State = OperationStatePending
+	op.done = make(chan error, 1)
+	f.queue.operations = append(f.queue.operations, op)
+	f.queue.lock.Unlock()
+
+	f.emitOperations()
+
+	select {
+	case f.queue.wake <- true:
+	default:
+		// The worker is already going to look at the queue
+	}
+
+	return <-op.done
+}
+
+func (f *ficsitCLI) processOperations() {
+	for range f.queue.wake {
+		for {
+			batch := f.nextOperationBatch()
+			if len(batch) == 0 {
+				break
+			}
+			f.emitOperations()
+			f.runOperationBatch(batch)
+		}
+	}
+}
+
+func (f *ficsitCLI) nextOperationBatch() []*queuedOperation {
+	f.queue.lock.Lock()
+	defer f.queue.lock.Unlock()
+
+	var batch []*queuedOperation
+	for _, op := range f.queue.operations {
+		if op.State != OperationStatePending {
+			continue
+		}
+		if len(batch) > 0 {
+			first := batch[0]
+			if !first.collapsible || !op.collapsible || op.run != nil || op.Installation != first.Installation {
+				break
+			}
+		}
+		batch = append(batch, op)
+	}
+
+	for _, op := range batch {
+		op.State = OperationStateRunning
+	}
+
+	return batch
+}
+
+func (f *ficsitCLI) runOperationBatch(batch []*queuedOperation) {
+	if batch[0].run != nil {
+		f.runStandaloneOperation(batch[0])
+		return
+	}
+
+	installation := f.GetInstallation(batch[0].Installation)
+	if installation == nil {
+		for _, op := range batch {
+			f.finishOperation(op, fmt.Errorf("installation %s not found", op.Installation))
+		}
+		return
+	}
+
+	l := slog.With(slog.String("install", installation.Path))
+
+	snapshot, err := f.snapshotInstall(installation)
+	if err != nil {
+		l.Error("failed to snapshot installation", slog.Any("error", err))
+		for _, op := range batch {
+			f.finishOperation(op, fmt.Errorf("failed to snapshot installation: %w", err))
+		}
+		return
+	}
+
+	applied := make([]*queuedOperation, 0, len(batch))
+	for _, op := range batch {
+		f.profileLock.Lock()
+		opSnapshot := f.snapshotProfileState(installation)
+		err := op.apply(installation)
+		var restoreErr error
+		if err != nil {
+			// Undo whatever the operation changed before failing, the other operations in the batch can still go ahead
+			restoreErr = f.restoreSnapshot(installation, opSnapshot)
+		}
+		f.profileLock.Unlock()
+		if err != nil {
+			if restoreErr != nil {
+				l.Error("failed to restore profile state", slog.Any("error", restoreErr))
+			}
+			f.finishOperation(op, err)
+			continue
+		}
+		applied = append(applied, op)
+	}
+
+	if len(applied) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(applied))
+	for _, op := range applied {
+		ids = append(ids, op.ID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f.queue.lock.Lock()
+	f.queue.running = ids
+	f.queue.cancel = cancel
+	f.queue.lock.Unlock()
+
+	f.setProgress(&Progress{
+		Item:     applied[0].Item,
+		Phase:    ProgressPhaseResolving,
+		Progress: -1,
+	})
+
+	installErr := f.validateInstall(ctx, installation, applied[0].Item)
+	if installErr == nil {
+		for _, op := range applied {
+			if op.verify == nil {
+				continue
+			}
+			installErr = op.verify(installation)
+			if installErr != nil {
+				break
+			}
+		}
+	}
+
+	f.queue.lock.Lock()
+	f.queue.cancel = nil
+	f.queue.lock.Unlock()
+
+	rolledBack := false
+	var conflict *ConflictReport
+	if installErr == nil {
+		historyOperations := make([]InstallHistoryOperation, 0, len(applied))
+		for _, op := range applied {
+			historyOperations = append(historyOperations, InstallHistoryOperation{Type: op.Type, Item: op.Item})
+		}
+		f.recordInstallHistory(installation, historyOperations)
+	} else {
+		l.Error("failed to validate installation, rolling back", slog.Any("error", installErr))
+		conflict = f.asConflictReport(installErr, installation)
+		rollbackErr := f.rollback(installation, snapshot, applied[0].Item)
+		if rollbackErr != nil {
+			l.Error("failed to roll back", slog.Any("error", rollbackErr))
+		} else {
+			rolledBack = true
+		}
+	}
+
+	f.setProgress(nil)
+
+	f.queue.lock.Lock()
+	f.queue.running = nil
+	for _, op := range applied {
+		op.RolledBack = rolledBack
+		op.Conflict = conflict
+	}
+	f.queue.lock.Unlock()
+
+	for _, op := range applied {
+		f.finishOperation(op, installErr)
+	}
+}
+
+func (f *ficsitCLI) runStandaloneOperation(op *queuedOperation) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f.queue.lock.Lock()
+	f.queue.running = []string{op.ID}
+	f.queue.cancel = cancel
+	f.queue.lock.Unlock()
+
+	f.setProgress(&Progress{
+		Item:     op.Item,
+		Phase:    ProgressPhaseResolving,
+		Progress: -1,
+	})
+
+	err := op.run(ctx)
+
+	f.setProgress(nil)
+
+	f.queue.lock.Lock()
+	f.queue.cancel = nil
+	f.queue.running = nil
+	f.queue.lock.Unlock()
+
+	f.finishOperation(op, err)
+}
+
+func (f *ficsitCLI) rollback(installation *cli.Installation, snapshot *installSnapshot, progressItem string) error {
+	f.setProgress(&Progress{
+		Item:     progressItem,
+		Phase:    ProgressPhaseRollingBack,
+		Progress: -1,
+	})
+
+	f.profileLock.Lock()
+	err := f.restoreSnapshot(installation, snapshot)
+	f.profileLock.Unlock()
+	f.EmitGlobals()
+	if err != nil {
+		return err
+	}
+
+	// The rollback must not be cancelled, otherwise the installation could be left in an inconsistent state
+	return f.validateInstall(context.Background(), installation, progressItem)
+}
+
+func (f *ficsitCLI) finishOperation(op *queuedOperation, err error) {
+	l := slog.With(slog.String("operation", op.ID), slog.String("type", op.Type), slog.String("item", op.Item), slog.String("install", op.Installation))
+
+	f.queue.lock.Lock()
+	if err != nil {
+		op.State = OperationStateFailed
+		op.Error = err.Error()
+	} else {
+		op.State = OperationStateCompleted
+		// Completed operations are not kept around, only failed ones, so that they can be shown to the user
+		f.queue.operations = slices.DeleteFunc(f.queue.operations, func(o *queuedOperation) bool {
+			return o == op
+		})
+	}
+	f.queue.lock.Unlock()
+
+	if err != nil {
+		l.Error("operation failed", slog.Any("error", err))
+		appCommon.EventsEmit("operationFailed", op.Operation)
+		if op.RolledBack {
+			appCommon.EventsEmit("operationRolledBack", op.Operation, err.Error())
+		}
+	} else {
+		appCommon.EventsEmit("operationCompleted", op.Operation)
+	}
+	f.emitOperations()
+
+	op.done <- err
+}
+
+func (f *ficsitCLI) emitOperations() {
+	appCommon.EventsEmit("operations", f.GetOperations())
+}
+
+func (f *ficsitCLI) GetOperations() []Operation {
+	f.queue.lock.Lock()
+	defer f.queue.lock.Unlock()
+
+	operations := make([]Operation, 0, len(f.queue.operations))
+	for _, op := range f.queue.operations {
+		operations = append(operations, op.Operation)
+	}
+	return operations
+}
+
+func (f *ficsitCLI) ClearFailedOperations() {
+	f.queue.lock.Lock()
+	f.queue.operations = slices.DeleteFunc(f.queue.operations, func(op *queuedOperation) bool {
+		return op.State == OperationStateFailed
+	})
+	f.queue.lock.Unlock()
+
+	f.emitOperations()
+}
+
+// CancelOperation stops the download or extraction of the running operations.
+// The installation is then rolled back to the state it was in before those operations.
+func (f *ficsitCLI) CancelOperation() {
+	f.queue.lock.Lock()
+	defer f.queue.lock.Unlock()
+	if f.queue.cancel != nil {
+		slog.Info("cancelling running operations", slog.Any("operations", f.queue.running))
+		f.queue.cancel()
+	}
+}
+
+func (f *ficsitCLI) runningOperations() []string {
+	f.queue.lock.Lock()
+	defer f.queue.lock.Unlock()
+	return f.queue.running
+}
@@ -69,6 +69,8 @@ func (f *ficsitCLI) RemoveRemoteServer(path string) error {
 		return fmt.Errorf("failed to delete installation: %w", err)
 	}
 	f.installationMetadata.Delete(path)
+	f.deleteInstallHistory(path)
+	f.deleteUnattendedUpdates(path)
 	f.EmitGlobals()
 	return nil
 }
@@ -0,0 +1,168 @@
+package ficsitcli
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/installfinders/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
+)
+
+type modUpdateScheduler struct {
+	lock     sync.Mutex
+	ticker   *time.Ticker
+	checking bool
+	// Latest updates found for each installation
+	updates map[string][]Update
+}
+
+// StartModUpdateChecks periodically checks every installation for mod updates,
+// and applies them to the installations set to be updated unattended
+func (f *ficsitCLI) StartModUpdateChecks(interval time.Duration) {
+	f.updateScheduler.lock.Lock()
+	defer f.updateScheduler.lock.Unlock()
+
+	if f.updateScheduler.ticker != nil {
+		return
+	}
+	f.updateScheduler.ticker = time.NewTicker(interval)
+	go func() {
+		f.CheckAllForModUpdates()
+		for range f.updateScheduler.ticker.C {
+			f.CheckAllForModUpdates()
+		}
+	}()
+}
+
+// CheckAllForModUpdates checks every installation for mod updates now.
+// Installations are checked one after another, so that the API is not flooded with requests.
+func (f *ficsitCLI) CheckAllForModUpdates() map[string][]Update {
+	l := slog.With(slog.String("task", "checkAllForModUpdates"))
+
+	f.updateScheduler.lock.Lock()
+	if f.updateScheduler.checking {
+		updates := f.updateScheduler.updates
+		f.updateScheduler.lock.Unlock()
+		return updates
+	}
+	f.updateScheduler.checking = true
+	f.updateScheduler.lock.Unlock()
+
+	updates := make(map[string][]Update)
+	for _, path := range f.GetInstallations() {
+		installation := f.GetInstallation(path)
+		if installation == nil || installation.Vanilla {
+			continue
+		}
+
+		installUpdates, err := f.checkForUpdates(installation)
+		if err != nil {
+			l.Warn("failed to check for updates", slog.String("install", path), slog.Any("error", err))
+			continue
+		}
+		if len(installUpdates) > 0 {
+			updates[path] = installUpdates
+		}
+	}
+
+	f.updateScheduler.lock.Lock()
+	f.updateScheduler.checking = false
+	f.updateScheduler.updates = updates
+	f.updateScheduler.lock.Unlock()
+
+	appCommon.EventsEmit("modUpdatesAvailable", updates)
+
+	f.applyUnattendedUpdates(updates)
+
+	return updates
+}
+
+// GetModUpdates returns the updates found by the latest background check, grouped by installation
+func (f *ficsitCLI) GetModUpdates() map[string][]Update {
+	f.updateScheduler.lock.Lock()
+	defer f.updateScheduler.lock.Unlock()
+
+	if f.updateScheduler.updates == nil {
+		return map[string][]Update{}
+	}
+	return maps.Clone(f.updateScheduler.updates)
+}
+
+func (f *ficsitCLI) applyUnattendedUpdates(updates map[string][]Update) {
+	for path, installUpdates := range updates {
+		if !slices.Contains(settings.Settings.UnattendedUpdateInstalls, path) {
+			continue
+		}
+		l := slog.With(slog.String("task", "applyUnattendedUpdates"), slog.String("install", path))
+
+		metadata, ok := f.installationMetadata.Load(path)
+		if f.isGameRunning && (!ok || metadata.Info == nil || metadata.Info.Location != common.LocationTypeRemote) {
+			// The files of a local installation are in use while the game is running
+			l.Info("game is running, skipping unattended update")
+			continue
+		}
+
+		mods := make([]string, 0, len(installUpdates))
+		for _, update := range installUpdates {
+			mods = append(mods, update.Item)
+		}
+
+		l.Info("applying unattended updates", slog.Any("mods", mods))
+		err := f.updateMods(path, mods)
+		if err != nil {
+			l.Error("failed to apply unattended updates", slog.Any("error", err))
+			continue
+		}
+
+		f.updateScheduler.lock.Lock()
+		delete(f.updateScheduler.updates, path)
+		f.updateScheduler.lock.Unlock()
+		appCommon.EventsEmit("modUpdatesAvailable", f.GetModUpdates())
+	}
+}
+
+// SetUnattendedUpdates sets whether the background update check applies updates to the installation without asking
+func (f *ficsitCLI) SetUnattendedUpdates(path string, enabled bool) error {
+	if f.GetInstallation(path) == nil {
+		return fmt.Errorf("installation %s not found", path)
+	}
+
+	isEnabled := slices.Contains(settings.Settings.UnattendedUpdateInstalls, path)
+	if enabled == isEnabled {
+		return nil
+	}
+	if enabled {
+		settings.Settings.UnattendedUpdateInstalls = append(settings.Settings.UnattendedUpdateInstalls, path)
+	} else {
+		settings.Settings.UnattendedUpdateInstalls = slices.DeleteFunc(settings.Settings.UnattendedUpdateInstalls, func(p string) bool { return p == path })
+	}
+
+	err := settings.SaveSettings()
+	if err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	appCommon.EventsEmit("unattendedUpdateInstalls", settings.Settings.UnattendedUpdateInstalls)
+
+	return nil
+}
+
+func (f *ficsitCLI) GetUnattendedUpdateInstalls() []string {
+	if settings.Settings.UnattendedUpdateInstalls == nil {
+		return []string{}
+	}
+	return slices.Clone(settings.Settings.UnattendedUpdateInstalls)
+}
+
+func (f *ficsitCLI) deleteUnattendedUpdates(path string) {
+	if !slices.Contains(settings.Settings.UnattendedUpdateInstalls, path) {
+		return
+	}
+	settings.Settings.UnattendedUpdateInstalls = slices.DeleteFunc(settings.Settings.UnattendedUpdateInstalls, func(p string) bool { return p == path })
+	_ = settings.SaveSettings()
+}
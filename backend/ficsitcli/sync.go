@@ -0,0 +1,194 @@
+package ficsitcli
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+)
+
+type ModDifference struct {
+	ModReference string `json:"modReference"`
+	// Empty when the mod is not installed on that installation
+	VersionA string `json:"versionA"`
+	VersionB string `json:"versionB"`
+}
+
+type InstallationComparison struct {
+	// Mods installed on A, but not on B
+	MissingOnB []ModDifference `json:"missingOnB"`
+	// Mods installed on B, but not on A
+	MissingOnA       []ModDifference `json:"missingOnA"`
+	VersionMismatch  []ModDifference `json:"versionMismatch"`
+	SMLVersionA      string          `json:"smlVersionA"`
+	SMLVersionB      string          `json:"smlVersionB"`
+	SMLVersionDiffer bool            `json:"smlVersionDiffer"`
+	Identical        bool            `json:"identical"`
+}
+
+// CompareInstallations reports the differences between the mods installed on the two installations
+func (f *ficsitCLI) CompareInstallations(pathA string, pathB string) (*InstallationComparison, error) {
+	l := slog.With(slog.String("task", "compareInstallations"), slog.String("installA", pathA), slog.String("installB", pathB))
+
+	lockfileA, err := f.getInstalledLockfile(pathA)
+	if err != nil {
+		l.Error("failed to get lockfile", slog.String("install", pathA), slog.Any("error", err))
+		return nil, err
+	}
+	lockfileB, err := f.getInstalledLockfile(pathB)
+	if err != nil {
+		l.Error("failed to get lockfile", slog.String("install", pathB), slog.Any("error", err))
+		return nil, err
+	}
+
+	return compareLockfiles(lockfileA, lockfileB), nil
+}
+
+func compareLockfiles(lockfileA *resolver.LockFile, lockfileB *resolver.LockFile) *InstallationComparison {
+	comparison := &InstallationComparison{
+		MissingOnB:      []ModDifference{},
+		MissingOnA:      []ModDifference{},
+		VersionMismatch: []ModDifference{},
+		SMLVersionA:     lockfileA.Mods["SML"].Version,
+		SMLVersionB:     lockfileB.Mods["SML"].Version,
+	}
+	comparison.SMLVersionDiffer = comparison.SMLVersionA != comparison.SMLVersionB
+
+	for modReference, modA := range lockfileA.Mods {
+		if modReference == "SML" {
+			continue
+		}
+		modB, ok := lockfileB.Mods[modReference]
+		if !ok {
+			comparison.MissingOnB = append(comparison.MissingOnB, ModDifference{
+				ModReference: modReference,
+				VersionA:     modA.Version,
+			})
+			continue
+		}
+		if modA.Version != modB.Version {
+			comparison.VersionMismatch = append(comparison.VersionMismatch, ModDifference{
+				ModReference: modReference,
+				VersionA:     modA.Version,
+				VersionB:     modB.Version,
+			})
+		}
+	}
+	for modReference, modB := range lockfileB.Mods {
+		if modReference == "SML" {
+			continue
+		}
+		if _, ok := lockfileA.Mods[modReference]; !ok {
+			comparison.MissingOnA = append(comparison.MissingOnA, ModDifference{
+				ModReference: modReference,
+				VersionB:     modB.Version,
+			})
+		}
+	}
+
+	for _, differences := range [][]ModDifference{comparison.MissingOnA, comparison.MissingOnB, comparison.VersionMismatch} {
+		sort.Slice(differences, func(i, j int) bool {
+			return differences[i].ModReference < differences[j].ModReference
+		})
+	}
+
+	comparison.Identical = !comparison.SMLVersionDiffer && len(comparison.MissingOnA) == 0 && len(comparison.MissingOnB) == 0 && len(comparison.VersionMismatch) == 0
+
+	return comparison
+}
+
+// getInstalledLockfile returns the lockfile of the mods actually installed,
+// which is empty if the installation has mods disabled
+func (f *ficsitCLI) getInstalledLockfile(path string) (*resolver.LockFile, error) {
+	installation := f.GetInstallation(path)
+	if installation == nil {
+		return nil, fmt.Errorf("installation %s not found", path)
+	}
+	if installation.Vanilla {
+		return resolver.NewLockfile(), nil
+	}
+	lockfile, err := installation.LockFile(f.ficsitCli)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lockfile of %s: %w", path, err)
+	}
+	if lockfile == nil {
+		return resolver.NewLockfile(), nil
+	}
+	return lockfile, nil
+}
+
+// SyncInstallation makes the target installation use the same profile and exactly the same mod versions as the source.
+// If the target cannot end up with the same versions, for example because a mod is not available for its platform,
+// the target is rolled back.
+func (f *ficsitCLI) SyncInstallation(source string, target string) error {
+	l := slog.With(slog.String("task", "syncInstallation"), slog.String("source", source), slog.String("target", target))
+
+	if source == target {
+		return fmt.Errorf("source and target are the same installation")
+	}
+
+	sourceInstallation := f.GetInstallation(source)
+	if sourceInstallation == nil {
+		l.Error("source installation not found")
+		return fmt.Errorf("installation %s not found", source)
+	}
+	if f.GetInstallation(target) == nil {
+		l.Error("target installation not found")
+		return fmt.Errorf("installation %s not found", target)
+	}
+
+	sourceLockfile, err := f.getInstalledLockfile(source)
+	if err != nil {
+		l.Error("failed to get source lockfile", slog.Any("error", err))
+		return err
+	}
+	sourceProfile := sourceInstallation.Profile
+	sourceVanilla := sourceInstallation.Vanilla
+
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "syncInstallation",
+			Item:         "__sync_install__",
+			Installation: target,
+		},
+		apply: func(installation *cli.Installation) error {
+			err := installation.SetProfile(f.ficsitCli, sourceProfile)
+			if err != nil {
+				l.Error("failed to set profile", slog.Any("error", err))
+				return fmt.Errorf("failed to set profile: %w", err)
+			}
+			installation.Vanilla = sourceVanilla
+
+			err = f.ficsitCli.Installations.Save()
+			if err != nil {
+				l.Error("failed to save installations", slog.Any("error", err))
+			}
+
+			if !sourceVanilla {
+				err = f.lockVersions(installation, sourceLockfile)
+				if err != nil {
+					l.Error("failed to write lockfile", slog.Any("error", err))
+					return err
+				}
+			}
+
+			f.EmitGlobals()
+
+			return nil
+		},
+		verify: func(installation *cli.Installation) error {
+			targetLockfile, err := f.getInstalledLockfile(installation.Path)
+			if err != nil {
+				return err
+			}
+			comparison := compareLockfiles(sourceLockfile, targetLockfile)
+			if !comparison.Identical {
+				l.Error("installation does not match source after sync", slog.Any("comparison", comparison))
+				return fmt.Errorf("could not install the same mod versions as %s", source)
+			}
+			return nil
+		},
+	})
+}
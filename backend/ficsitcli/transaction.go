@@ -0,0 +1,128 @@
+package ficsitcli
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+)
+
+// installSnapshot is the state an operation can change, so that it can be restored if the operation fails
+type installSnapshot struct {
+	profiles map[string]cli.Profile
+	profile  string
+	vanilla  bool
+
+	includesLockfile bool
+	lockfile         *resolver.LockFile
+}
+
+func (f *ficsitCLI) snapshotProfileState(installation *cli.Installation) *installSnapshot {
+	profiles := make(map[string]cli.Profile, len(f.ficsitCli.Profiles.Profiles))
+	for name, profile := range f.ficsitCli.Profiles.Profiles {
+		profiles[name] = cli.Profile{
+			Name:            profile.Name,
+			Mods:            maps.Clone(profile.Mods),
+			RequiredTargets: slices.Clone(profile.RequiredTargets),
+		}
+	}
+	return &installSnapshot{
+		profiles: profiles,
+		profile:  installation.Profile,
+		vanilla:  installation.Vanilla,
+	}
+}
+
+func (f *ficsitCLI) snapshotInstall(installation *cli.Installation) (*installSnapshot, error) {
+	snapshot := f.snapshotProfileState(installation)
+	lockfile, err := installation.LockFile(f.ficsitCli)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	snapshot.includesLockfile = true
+	snapshot.lockfile = lockfile
+	return snapshot, nil
+}
+
+// snapshotInstallation is like snapshotInstall, but leaves the profiles out,
+// for operations that only change which profile the installation uses
+func (f *ficsitCLI) snapshotInstallation(installation *cli.Installation) (*installSnapshot, error) {
+	lockfile, err := installation.LockFile(f.ficsitCli)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	return &installSnapshot{
+		profile:          installation.Profile,
+		vanilla:          installation.Vanilla,
+		includesLockfile: true,
+		lockfile:         lockfile,
+	}, nil
+}
+
+func (f *ficsitCLI) restoreSnapshot(installation *cli.Installation, snapshot *installSnapshot) error {
+	if snapshot.profiles != nil {
+		for name := range f.ficsitCli.Profiles.Profiles {
+			if _, ok := snapshot.profiles[name]; !ok {
+				_ = f.ficsitCli.Profiles.DeleteProfile(name)
+			}
+		}
+		for name, profile := range snapshot.profiles {
+			existing := f.ficsitCli.Profiles.GetProfile(name)
+			if existing == nil {
+				restored := profile
+				f.ficsitCli.Profiles.Profiles[name] = &restored
+				continue
+			}
+			existing.Mods = maps.Clone(profile.Mods)
+			existing.RequiredTargets = slices.Clone(profile.RequiredTargets)
+		}
+
+		err := f.ficsitCli.Profiles.Save()
+		if err != nil {
+			return fmt.Errorf("failed to save profiles: %w", err)
+		}
+	}
+
+	installation.Profile = snapshot.profile
+	installation.Vanilla = snapshot.vanilla
+
+	err := f.ficsitCli.Installations.Save()
+	if err != nil {
+		return fmt.Errorf("failed to save installations: %w", err)
+	}
+
+	if !snapshot.includesLockfile {
+		return nil
+	}
+
+	if snapshot.lockfile != nil {
+		err = installation.WriteLockFile(f.ficsitCli, snapshot.lockfile)
+		if err != nil {
+			return fmt.Errorf("failed to write lockfile: %w", err)
+		}
+		return nil
+	}
+
+	// There was no lockfile before, so the one created since must be removed
+	lockfilePath, err := installation.LockFilePath(f.ficsitCli)
+	if err != nil {
+		return fmt.Errorf("failed to get lockfile path: %w", err)
+	}
+	d, err := installation.GetDisk()
+	if err != nil {
+		return fmt.Errorf("failed to get disk: %w", err)
+	}
+	exists, err := d.Exists(lockfilePath)
+	if err != nil {
+		return fmt.Errorf("failed to check if lockfile exists: %w", err)
+	}
+	if exists {
+		err = d.Remove(lockfilePath)
+		if err != nil {
+			return fmt.Errorf("failed to remove lockfile: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package ficsitcli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+	"github.com/spf13/viper"
+)
+
+// Upper bound on the number of times the profile is resolved while searching for mods to disable
+const maxTriageResolutions = 500
+
+type ConflictTriage struct {
+	Installation string `json:"installation"`
+	Profile      string `json:"profile"`
+	// Whether the profile already resolves, in which case nothing needs to be disabled
+	Resolvable bool `json:"resolvable"`
+	// The smallest set of mods that have to be disabled for the profile to resolve
+	Disable []string          `json:"disable"`
+	Names   map[string]string `json:"names"`
+	// Why the profile does not resolve as it is
+	Conflict *ConflictReport `json:"conflict,omitempty"`
+	// What would be installed after disabling the mods
+	Changes *ChangePlan `json:"changes,omitempty"`
+}
+
+// AutoResolveConflicts finds the smallest set of mods to disable in the profile of the selected installation
+// so that it resolves against the installation's game version. Nothing is changed, the result should be
+// confirmed and passed to ApplyConflictTriage.
+func (f *ficsitCLI) AutoResolveConflicts() (*ConflictTriage, error) {
+	l := slog.With(slog.String("task", "autoResolveConflicts"))
+
+	selectedInstallation := f.GetSelectedInstall()
+	if selectedInstallation == nil {
+		return nil, fmt.Errorf("no installation selected")
+	}
+	l = l.With(slog.String("install", selectedInstallation.Path), slog.String("profile", selectedInstallation.Profile))
+
+	profile := f.GetProfile(selectedInstallation.Profile)
+	if profile == nil {
+		return nil, fmt.Errorf("profile %s not found", selectedInstallation.Profile)
+	}
+
+	gameVersion, err := selectedInstallation.GetGameVersion(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get game version", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get game version: %w", err)
+	}
+
+	platform, err := selectedInstallation.GetPlatform(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get platform", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get platform: %w", err)
+	}
+
+	lockfile, err := selectedInstallation.LockFile(f.ficsitCli)
+	if err != nil {
+		l.Error("failed to get lockfile", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get lockfile: %w", err)
+	}
+	if lockfile == nil {
+		lockfile = resolver.NewLockfile()
+	}
+
+	triage := &ConflictTriage{
+		Installation: selectedInstallation.Path,
+		Profile:      profile.Name,
+		Disable:      []string{},
+		Names:        map[string]string{},
+	}
+
+	res := resolver.NewDependencyResolver(f.ficsitCli.Provider, viper.GetString("api-base"))
+	resolutions := 0
+	resolveWithout := func(disabled []string) (*cli.Profile, *resolver.LockFile, error) {
+		resolutions++
+		triageProfile := copyProfile(profile)
+		for _, mod := range disabled {
+			triageProfile.SetModEnabled(mod, false)
+		}
+		resolved, err := triageProfile.Resolve(res, lockfile, gameVersion)
+		return triageProfile, resolved, err //nolint:wrapcheck
+	}
+
+	_, _, err = resolveWithout(nil)
+	if err == nil {
+		triage.Resolvable = true
+		return triage, nil
+	}
+	var solvingError resolver.DependencyResolverError
+	if !errors.As(err, &solvingError) {
+		l.Error("failed to resolve dependencies", slog.Any("error", err))
+		return nil, err //nolint:wrapcheck
+	}
+	triage.Conflict = f.explainConflict(solvingError, gameVersion, lockfile)
+
+	// Breadth first search over sets of disabled mods, so the first set that resolves is one of the smallest.
+	// Only the profile mods involved in the conflict of each set are tried, since disabling anything else
+	// cannot fix that conflict.
+	queue := [][]string{{}}
+	candidates := map[string][]string{"": conflictingProfileMods(solvingError, profile, nil)}
+	visited := map[string]bool{"": true}
+	for len(queue) > 0 {
+		disabled := queue[0]
+		queue = queue[1:]
+
+		for _, mod := range candidates[strings.Join(disabled, ",")] {
+			next := append(slices.Clone(disabled), mod)
+			sort.Strings(next)
+			key := strings.Join(next, ",")
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+
+			if resolutions >= maxTriageResolutions {
+				l.Warn("gave up searching for mods to disable", slog.Int("resolutions", resolutions))
+				return nil, fmt.Errorf("could not find mods to disable within %d attempts", maxTriageResolutions)
+			}
+
+			triageProfile, resolved, err := resolveWithout(next)
+			if err == nil {
+				triage.Disable = next
+				for _, modReference := range next {
+					triage.Names[modReference] = modReference
+					if name, ok := triage.Conflict.Names[modReference]; ok {
+						triage.Names[modReference] = name
+					}
+				}
+				triage.Changes = f.diffLockfiles(lockfile, resolved, triageProfile, platform.TargetName)
+				l.Info("found mods to disable", slog.Any("mods", next), slog.Int("resolutions", resolutions))
+				return triage, nil
+			}
+			if !errors.As(err, &solvingError) {
+				l.Error("failed to resolve dependencies", slog.Any("error", err))
+				return nil, err //nolint:wrapcheck
+			}
+
+			candidates[key] = conflictingProfileMods(solvingError, profile, next)
+			queue = append(queue, next)
+		}
+	}
+
+	return nil, fmt.Errorf("the profile cannot be resolved by disabling mods")
+}
+
+// conflictingProfileMods returns the enabled mods of the profile, other than the already disabled ones,
+// that the profile requires directly in the conflict
+func conflictingProfileMods(solvingError resolver.DependencyResolverError, profile *cli.Profile, disabled []string) []string {
+	mods := []string{}
+	visited := map[string]bool{}
+	for _, requirement := range analyzeConflict(solvingError, 0).Requirements {
+		if requirement.ModReference != "" {
+			continue
+		}
+		mod := requirement.Dependency
+		if visited[mod] || !profile.IsModEnabled(mod) || slices.Contains(disabled, mod) {
+			continue
+		}
+		visited[mod] = true
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+	return mods
+}
+
+// ApplyConflictTriage disables the mods found by AutoResolveConflicts in the profile
+func (f *ficsitCLI) ApplyConflictTriage(triage ConflictTriage) error {
+	l := slog.With(slog.String("task", "applyConflictTriage"), slog.String("profile", triage.Profile), slog.Any("mods", triage.Disable))
+
+	if len(triage.Disable) == 0 {
+		return nil
+	}
+
+	if f.GetInstallation(triage.Installation) == nil {
+		l.Error("installation not found", slog.String("install", triage.Installation))
+		return fmt.Errorf("installation %s not found", triage.Installation)
+	}
+
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "applyConflictTriage",
+			Item:         "__conflict_triage__",
+			Installation: triage.Installation,
+		},
+		apply: func(installation *cli.Installation) error {
+			if installation.Profile != triage.Profile {
+				return fmt.Errorf("installation no longer uses profile %s", triage.Profile)
+			}
+			profile := f.GetProfile(triage.Profile)
+			if profile == nil {
+				return fmt.Errorf("profile %s not found", triage.Profile)
+			}
+
+			for _, mod := range triage.Disable {
+				profile.SetModEnabled(mod, false)
+			}
+
+			err := f.ficsitCli.Profiles.Save()
+			if err != nil {
+				l.Error("failed to save profile", slog.Any("error", err))
+			}
+
+			return nil
+		},
+	})
+}
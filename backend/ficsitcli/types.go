@@ -16,10 +16,50 @@ type installationMetadata struct {
 	Info  *common.Installation `json:"info"`
 }
 
+type ProgressPhase string
+
+const (
+	ProgressPhaseResolving   ProgressPhase = "resolving"
+	ProgressPhaseRemoving    ProgressPhase = "removing"
+	ProgressPhaseDownloading ProgressPhase = "downloading"
+	ProgressPhaseExtracting  ProgressPhase = "extracting"
+	ProgressPhaseRollingBack ProgressPhase = "rollingBack"
+	// Changes are being applied to multiple installations, each of which reports its own progress as well
+	ProgressPhaseApplying ProgressPhase = "applying"
+)
+
+type CountProgress struct {
+	Completed int64 `json:"completed"`
+	Total     int64 `json:"total"`
+}
+
+type ModProgress struct {
+	ModReference string        `json:"modReference"`
+	Version      string        `json:"version"`
+	Download     CountProgress `json:"download"`
+	Extract      CountProgress `json:"extract"`
+	Downloading  bool          `json:"downloading"`
+	Complete     bool          `json:"complete"`
+}
+
 type Progress struct {
-	Item     string  `json:"item"`
-	Message  string  `json:"message"`
+	Item  string        `json:"item"`
+	Phase ProgressPhase `json:"phase"`
+	// Between 0 and 1, or -1 if it cannot be determined
 	Progress float64 `json:"progress"`
+	// Mods in the current phase
+	Mods CountProgress `json:"mods"`
+	// Bytes in the current phase
+	Bytes CountProgress `json:"bytes"`
+	// Bytes per second
+	Speed float64 `json:"speed"`
+	// Estimated seconds until the current phase finishes, 0 if unknown
+	ETA         float64       `json:"eta"`
+	ModProgress []ModProgress `json:"modProgress"`
+	// Installations done, for ProgressPhaseApplying
+	Installations CountProgress `json:"installations"`
+	// IDs of the queued operations this progress belongs to
+	Operations []string `json:"operations"`
 }
 
 var AllInstallationStates = []struct {
@@ -31,3 +71,15 @@ var AllInstallationStates = []struct {
 	{InstallStateInvalid, "INVALID"},
 	{InstallStateValid, "VALID"},
 }
+
+var AllProgressPhases = []struct {
+	Value  ProgressPhase
+	TSName string
+}{
+	{ProgressPhaseResolving, "RESOLVING"},
+	{ProgressPhaseRemoving, "REMOVING"},
+	{ProgressPhaseDownloading, "DOWNLOADING"},
+	{ProgressPhaseExtracting, "EXTRACTING"},
+	{ProgressPhaseRollingBack, "ROLLING_BACK"},
+	{ProgressPhaseApplying, "APPLYING"},
+}
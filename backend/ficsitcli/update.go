@@ -1,19 +1,36 @@
 package ficsitcli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"time"
 
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
 	"github.com/satisfactorymodding/ficsit-cli/cli"
 	resolver "github.com/satisfactorymodding/ficsit-resolver"
 	"github.com/spf13/viper"
+
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
 )
 
 type Update struct {
 	Item           string `json:"item"`
 	CurrentVersion string `json:"currentVersion"`
 	NewVersion     string `json:"newVersion"`
+	// Changelogs of the versions after the current one, up to the new one, newest first
+	Changelogs  []VersionChangelog `json:"changelogs"`
+	ReleaseDate *time.Time         `json:"releaseDate,omitempty"`
+	// SML version conditions of the current and new version
+	CurrentSMLVersion string `json:"currentSmlVersion,omitempty"`
+	NewSMLVersion     string `json:"newSmlVersion,omitempty"`
+	RaisesSMLVersion  bool   `json:"raisesSmlVersion"`
+	// Minimum game versions of the current and new version
+	CurrentGameVersion int  `json:"currentGameVersion,omitempty"`
+	NewGameVersion     int  `json:"newGameVersion,omitempty"`
+	RaisesGameVersion  bool `json:"raisesGameVersion"`
 }
 
 func (f *ficsitCLI) CheckForUpdates() ([]Update, error) {
@@ -22,7 +39,12 @@ func (f *ficsitCLI) CheckForUpdates() ([]Update, error) {
 	if selectedInstallation == nil {
 		return []Update{}, nil
 	}
-	l := slog.With(slog.String("task", "checkForUpdates"))
+
+	return f.checkForUpdates(selectedInstallation)
+}
+
+func (f *ficsitCLI) checkForUpdates(selectedInstallation *cli.Installation) ([]Update, error) {
+	l := slog.With(slog.String("task", "checkForUpdates"), slog.String("install", selectedInstallation.Path))
 
 	currentLockfile, err := selectedInstallation.LockFile(f.ficsitCli)
 	if err != nil {
@@ -34,7 +56,12 @@ func (f *ficsitCLI) CheckForUpdates() ([]Update, error) {
 		return nil, nil
 	}
 
+	// The check runs outside the queue, so the profile must not be read while an operation changes it
+	f.profileLock.RLock()
 	profile := f.GetProfile(selectedInstallation.Profile)
+	profileName := profile.Name
+	profileMods := maps.Clone(profile.Mods)
+	f.profileLock.RUnlock()
 
 	res := resolver.NewDependencyResolver(f.ficsitCli.Provider, viper.GetString("api-base"))
 
@@ -48,10 +75,28 @@ func (f *ficsitCLI) CheckForUpdates() ([]Update, error) {
 		Name: "Update temp",
 		Mods: make(map[string]cli.ProfileMod),
 	}
-	for modReference, modData := range profile.Mods {
+	for modReference, modData := range profileMods {
+		version := updateConstraint(modReference, modData.Version, currentLockfile.Mods[modReference].Version)
+		if f.isModPinned(profileName, modReference) {
+			// Pinned mods stay at the installed version
+			version = modData.Version
+			if lockedMod, ok := currentLockfile.Mods[modReference]; ok {
+				version = lockedMod.Version
+			}
+		}
 		updateProfile.Mods[modReference] = cli.ProfileMod{
 			Enabled: modData.Enabled,
-			Version: ">=0.0.0",
+			Version: version,
+		}
+	}
+	// Installed dependencies with ignored updates must also be kept away from the ignored versions
+	for modReference, lockedMod := range currentLockfile.Mods {
+		if _, ok := updateProfile.Mods[modReference]; ok || ignoredVersions(modReference) == nil {
+			continue
+		}
+		updateProfile.Mods[modReference] = cli.ProfileMod{
+			Enabled: true,
+			Version: allowedUpdateVersions(modReference, lockedMod.Version),
 		}
 	}
 	newLockfile, err := updateProfile.Resolve(res, nil, gameVersion)
@@ -68,7 +113,7 @@ func (f *ficsitCLI) CheckForUpdates() ([]Update, error) {
 
 	for modReference, newLockedMod := range newLockfile.Mods {
 		if prevLockedMod, ok := currentLockfile.Mods[modReference]; ok {
-			if newLockedMod.Version != prevLockedMod.Version {
+			if newLockedMod.Version != prevLockedMod.Version && !isVersionIgnored(modReference, newLockedMod.Version) {
 				updates = append(updates, Update{
 					Item:           modReference,
 					CurrentVersion: prevLockedMod.Version,
@@ -78,66 +123,199 @@ func (f *ficsitCLI) CheckForUpdates() ([]Update, error) {
 		}
 	}
 
+	f.addUpdateDetails(context.Background(), updates)
+
 	return updates, nil
 }
 
 func (f *ficsitCLI) UpdateMods(mods []string) error {
-	l := slog.With(slog.String("task", "updateMods"))
-
-	if f.progress != nil {
-		l.Error("another operation in progress")
-		return fmt.Errorf("another operation in progress")
-	}
-
 	selectedInstallation := f.GetSelectedInstall()
 
 	if selectedInstallation == nil {
 		return fmt.Errorf("no installation selected")
 	}
 
-	profile := f.GetProfile(selectedInstallation.Profile)
+	return f.updateMods(selectedInstallation.Path, mods)
+}
+
+// UpdateAllMods updates the mods, or every mod with an available update if none are given,
+// and returns the mods that were updated
+func (f *ficsitCLI) UpdateAllMods(mods []string) ([]string, error) {
+	if len(mods) == 0 {
+		updates, err := f.CheckForUpdates()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for updates: %w", err)
+		}
+		for _, update := range updates {
+			mods = append(mods, update.Item)
+		}
+	}
+	if len(mods) == 0 {
+		return []string{}, nil
+	}
+
+	err := f.UpdateMods(mods)
+	if err != nil {
+		return nil, err
+	}
+	return mods, nil
+}
+
+func (f *ficsitCLI) updateMods(installPath string, mods []string) error {
+	var previousLockfile *resolver.LockFile
+
+	return f.queueOperation(&queuedOperation{
+		Operation: Operation{
+			Type:         "updateMods",
+			Item:         "__update__",
+			Installation: installPath,
+		},
+		collapsible: true,
+		apply: func(installation *cli.Installation) error {
+			l := slog.With(slog.String("task", "updateMods"), slog.String("install", installation.Path))
+
+			var err error
+			previousLockfile, err = installation.LockFile(f.ficsitCli)
+			if err != nil {
+				l.Error("failed to get current lockfile", slog.Any("error", err))
+				return fmt.Errorf("failed to get current lockfile: %w", err)
+			}
+
+			profile := f.GetProfile(installation.Profile)
+			constraints := f.updateConstraints(profile, previousLockfile, mods, l)
+			toUpdate := make([]string, 0, len(constraints))
+			originalConstraints := make(map[string]string, len(constraints))
+			for modReference, constraint := range constraints {
+				originalConstraints[modReference] = profile.Mods[modReference].Version
+				profile.Mods[modReference] = cli.ProfileMod{
+					Enabled: profile.Mods[modReference].Enabled,
+					Version: constraint,
+				}
+				toUpdate = append(toUpdate, modReference)
+			}
+
+			err = installation.UpdateMods(f.ficsitCli, toUpdate)
+
+			// The ignored versions are only excluded while picking the new versions, which are then kept by the lockfile.
+			// The profile keeps the user's own constraints, so that unignoring a version makes it available again.
+			for _, modReference := range toUpdate {
+				profile.Mods[modReference] = cli.ProfileMod{
+					Enabled: profile.Mods[modReference].Enabled,
+					Version: originalConstraints[modReference],
+				}
+			}
+
+			saveErr := f.ficsitCli.Profiles.Save()
+			if saveErr != nil {
+				l.Error("failed to save profile", slog.Any("error", saveErr))
+			}
+
+			if err != nil {
+				l.Error("failed to update mods", slog.Any("error", err))
+				var solvingError resolver.DependencyResolverError
+				if errors.As(err, &solvingError) {
+					return solvingError
+				}
+				return err //nolint:wrapcheck
+			}
+
+			return nil
+		},
+		verify: func(installation *cli.Installation) error {
+			return f.checkPinnedModsUnchanged(installation, previousLockfile)
+		},
+	})
+}
+
+// updateConstraints returns the version constraints to resolve the mods with when updating them.
+// Pinned mods and mods that are not in the profile are left out, since they must not be updated.
+func (f *ficsitCLI) updateConstraints(profile *cli.Profile, lockfile *resolver.LockFile, mods []string, l *slog.Logger) map[string]string {
+	constraints := make(map[string]string, len(mods))
 	for _, modReference := range mods {
 		if _, ok := profile.Mods[modReference]; !ok {
 			l.Warn("mod not found in profile", slog.String("mod", modReference))
 			continue
 		}
-		profile.Mods[modReference] = cli.ProfileMod{
-			Enabled: profile.Mods[modReference].Enabled,
-			Version: ">=0.0.0",
+		if f.isModPinned(profile.Name, modReference) {
+			l.Info("skipping pinned mod", slog.String("mod", modReference))
+			continue
+		}
+		installedVersion := ""
+		if lockfile != nil {
+			installedVersion = lockfile.Mods[modReference].Version
 		}
+		constraints[modReference] = updateConstraint(modReference, profile.Mods[modReference].Version, installedVersion)
 	}
+	return constraints
+}
 
-	err := f.ficsitCli.Profiles.Save()
+// updateConstraint returns the constraint to resolve the mod with when updating it,
+// which is the user's constraint limited to the versions that are not ignored, or the installed one.
+// If the user's constraint only allows ignored versions, it is kept as is.
+func updateConstraint(mod string, userConstraint string, installedVersion string) string {
+	allowed, err := semver.NewConstraint(allowedUpdateVersions(mod, installedVersion))
 	if err != nil {
-		l.Error("failed to save profile", slog.Any("error", err))
+		return userConstraint
 	}
-
-	err = selectedInstallation.UpdateMods(f.ficsitCli, mods)
+	constraint, err := semver.NewConstraint(userConstraint)
 	if err != nil {
-		l.Error("failed to update mods", slog.Any("error", err))
-		var solvingError resolver.DependencyResolverError
-		if errors.As(err, &solvingError) {
-			return solvingError
-		}
-		return err //nolint:wrapcheck
+		return allowed.String()
 	}
-
-	f.progress = &Progress{
-		Item:     "__update__",
-		Message:  "Updating...",
-		Progress: -1,
+	intersection := constraint.Intersect(allowed)
+	if intersection.IsEmpty() {
+		return userConstraint
 	}
+	return intersection.String()
+}
 
-	f.setProgress(f.progress)
-
-	defer f.setProgress(nil)
-
-	err = f.validateInstall(selectedInstallation, "__update__")
+// ignoredVersions returns the versions of the mod that updates are ignored for, or nil if there are none
+func ignoredVersions(mod string) *semver.Constraint {
+	var ignored *semver.Constraint
+	for _, entry := range settings.Settings.IgnoredUpdates[mod] {
+		constraint, err := semver.NewConstraint(entry)
+		if err != nil {
+			slog.Warn("invalid ignored update", slog.String("mod", mod), slog.String("entry", entry), slog.Any("error", err))
+			continue
+		}
+		if ignored == nil {
+			ignored = &constraint
+		} else {
+			union := ignored.Union(constraint)
+			ignored = &union
+		}
+	}
+	return ignored
+}
 
+func isVersionIgnored(mod string, version string) bool {
+	ignored := ignoredVersions(mod)
+	if ignored == nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
 	if err != nil {
-		l.Error("failed to validate installation", slog.Any("error", err))
-		return err
+		return false
 	}
+	return ignored.Contains(v)
+}
 
-	return nil
+// allowedUpdateVersions returns the version constraint that allows updating the mod to any version that is not ignored.
+// The installed version is always allowed, so that ignoring it does not force a change.
+func allowedUpdateVersions(mod string, installedVersion string) string {
+	ignored := ignoredVersions(mod)
+	if ignored == nil {
+		return ">=0.0.0"
+	}
+	allowed, err := semver.NewConstraint(">=0.0.0")
+	if err != nil {
+		return ">=0.0.0"
+	}
+	allowed = allowed.Difference(*ignored)
+	if v, err := semver.NewVersion(installedVersion); err == nil {
+		allowed = allowed.Union(semver.SingleVersionConstraint(v))
+	}
+	if allowed.IsEmpty() {
+		return installedVersion
+	}
+	return allowed.String()
 }
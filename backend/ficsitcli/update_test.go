@@ -0,0 +1,176 @@
+package ficsitcli
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
+	"github.com/satisfactorymodding/ficsit-cli/cli"
+	resolver "github.com/satisfactorymodding/ficsit-resolver"
+
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
+)
+
+func setIgnoredUpdates(t *testing.T, ignored map[string][]string) {
+	previous := settings.Settings.IgnoredUpdates
+	settings.Settings.IgnoredUpdates = ignored
+	t.Cleanup(func() { settings.Settings.IgnoredUpdates = previous })
+}
+
+// checkConstraint checks which versions the constraint allows, rather than how it is formatted
+func checkConstraint(t *testing.T, constraint string, allowed []string, notAllowed []string) {
+	t.Helper()
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		t.Fatalf("invalid constraint %s: %v", constraint, err)
+	}
+	contains := func(version string) bool {
+		v, err := semver.NewVersion(version)
+		if err != nil {
+			t.Fatalf("invalid version %s: %v", version, err)
+		}
+		return c.Contains(v)
+	}
+	for _, version := range allowed {
+		if !contains(version) {
+			t.Errorf("constraint %s does not allow %s", constraint, version)
+		}
+	}
+	for _, version := range notAllowed {
+		if contains(version) {
+			t.Errorf("constraint %s allows %s", constraint, version)
+		}
+	}
+}
+
+func TestIgnoredVersions(t *testing.T) {
+	setIgnoredUpdates(t, map[string][]string{
+		"Range":   {"2.x"},
+		"Invalid": {"not a version", "1.1.0"},
+	})
+
+	if ignoredVersions("None") != nil {
+		t.Error("expected no ignored versions")
+	}
+
+	tests := []struct {
+		mod     string
+		version string
+		ignored bool
+	}{
+		{"Range", "2.0.0", true},
+		{"Range", "2.5.1", true},
+		{"Range", "1.9.0", false},
+		{"Range", "3.0.0", false},
+		{"Invalid", "1.1.0", true},
+		{"Invalid", "1.2.0", false},
+		{"None", "1.0.0", false},
+		{"Range", "not a version", false},
+	}
+	for _, test := range tests {
+		if got := isVersionIgnored(test.mod, test.version); got != test.ignored {
+			t.Errorf("isVersionIgnored(%s, %s) = %v, want %v", test.mod, test.version, got, test.ignored)
+		}
+	}
+}
+
+func TestAllowedUpdateVersions(t *testing.T) {
+	setIgnoredUpdates(t, map[string][]string{
+		"Range":    {"2.x"},
+		"Versions": {"2.1.0", "2.2.0"},
+		"All":      {">=0.0.0"},
+	})
+
+	if got := allowedUpdateVersions("None", "1.0.0"); got != ">=0.0.0" {
+		t.Errorf("got %s for a mod without ignored versions, want >=0.0.0", got)
+	}
+
+	tests := []struct {
+		name       string
+		mod        string
+		installed  string
+		allowed    []string
+		notAllowed []string
+	}{
+		{
+			name:       "range",
+			mod:        "Range",
+			installed:  "1.0.0",
+			allowed:    []string{"1.0.0", "1.5.0", "3.0.0"},
+			notAllowed: []string{"2.0.0", "2.5.1"},
+		},
+		{
+			name:       "installed version is ignored",
+			mod:        "Versions",
+			installed:  "2.1.0",
+			allowed:    []string{"2.1.0", "2.3.0"},
+			notAllowed: []string{"2.2.0"},
+		},
+		{
+			name:       "everything ignored",
+			mod:        "All",
+			installed:  "1.0.0",
+			allowed:    []string{"1.0.0"},
+			notAllowed: []string{"0.9.0", "1.0.1", "2.0.0"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkConstraint(t, allowedUpdateVersions(test.mod, test.installed), test.allowed, test.notAllowed)
+		})
+	}
+}
+
+func TestUpdateConstraints(t *testing.T) {
+	setIgnoredUpdates(t, map[string][]string{
+		"Constrained": {"1.3.0"},
+		"Held":        {"2.x"},
+		"Outside":     {"2.x"},
+	})
+	previousPinned := settings.Settings.PinnedMods
+	settings.Settings.PinnedMods = map[string][]string{"Profile": {"Pinned"}}
+	t.Cleanup(func() { settings.Settings.PinnedMods = previousPinned })
+
+	profile := &cli.Profile{
+		Name: "Profile",
+		Mods: map[string]cli.ProfileMod{
+			"Constrained": {Version: "^1.2.0", Enabled: true},
+			"Free":        {Version: ">=0.0.0", Enabled: true},
+			"Pinned":      {Version: ">=0.0.0", Enabled: true},
+			"Held":        {Version: "^2.0.0", Enabled: true},
+			"Outside":     {Version: "^2.0.0", Enabled: true},
+		},
+	}
+	lockfile := &resolver.LockFile{
+		Mods: map[string]resolver.LockedMod{
+			"Constrained": {Version: "1.2.0"},
+			"Free":        {Version: "1.0.0"},
+			"Pinned":      {Version: "1.0.0"},
+			"Held":        {Version: "2.1.0"},
+			"Outside":     {Version: "3.0.0"},
+		},
+	}
+
+	f := &ficsitCLI{}
+	constraints := f.updateConstraints(profile, lockfile, []string{"Constrained", "Free", "Pinned", "Held", "Outside", "Missing"}, slog.Default())
+
+	for _, mod := range []string{"Pinned", "Missing"} {
+		if constraint, ok := constraints[mod]; ok {
+			t.Errorf("got constraint %s for %s, want none", constraint, mod)
+		}
+	}
+	if len(constraints) != 4 {
+		t.Errorf("got constraints %v", constraints)
+	}
+	if constraints["Free"] != ">=0.0.0" {
+		t.Errorf("got constraint %s for Free, want >=0.0.0", constraints["Free"])
+	}
+	// The profile constraint is limited to the versions that are not ignored
+	checkConstraint(t, constraints["Constrained"], []string{"1.2.0", "1.2.5", "1.4.0"}, []string{"1.3.0", "2.0.0", "1.1.0"})
+	// Only the installed version is both allowed by the profile and not ignored
+	checkConstraint(t, constraints["Held"], []string{"2.1.0"}, []string{"2.0.0", "2.2.0", "3.0.0"})
+	// Nothing allowed by the profile is left, so the profile constraint is kept as is
+	if constraints["Outside"] != "^2.0.0" {
+		t.Errorf("got constraint %s for Outside, want ^2.0.0", constraints["Outside"])
+	}
+}
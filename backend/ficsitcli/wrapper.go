@@ -3,13 +3,14 @@ package ficsitcli
 import (
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/mitchellh/go-ps"
 	"github.com/puzpuzpuz/xsync/v3"
 	"github.com/satisfactorymodding/ficsit-cli/cli"
 	"github.com/satisfactorymodding/ficsit-cli/cli/provider"
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/spf13/viper"
 
 	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
@@ -21,6 +22,15 @@ type ficsitCLI struct {
 	installFindErrors    []error
 	progress             *Progress
 	isGameRunning        bool
+	queue                *operationQueue
+	// Held while the profiles are changed, by the queue worker and by changes to profiles not used by the selected installation,
+	// so that code running outside the queue can copy a profile safely
+	profileLock       sync.RWMutex
+	history           *installHistory
+	downloadSemaphore chan int
+	cacheLocks        *xsync.MapOf[string, *sync.Mutex]
+	installProgress   *xsync.MapOf[string, *Progress]
+	updateScheduler   *modUpdateScheduler
 }
 
 var FicsitCLI *ficsitCLI
@@ -35,11 +45,21 @@ func Init() error {
 	}
 	ficsitCli.Provider.(*provider.MixedProvider).Offline = settings.Settings.Offline
 
-	FicsitCLI = &ficsitCLI{ficsitCli: ficsitCli, installationMetadata: xsync.NewMapOf[string, installationMetadata]()}
+	FicsitCLI = &ficsitCLI{
+		ficsitCli:            ficsitCli,
+		installationMetadata: xsync.NewMapOf[string, installationMetadata](),
+		queue:                newOperationQueue(),
+		history:              loadInstallHistory(),
+		downloadSemaphore:    make(chan int, viper.GetInt("concurrent-downloads")),
+		cacheLocks:           xsync.NewMapOf[string, *sync.Mutex](),
+		installProgress:      xsync.NewMapOf[string, *Progress](),
+		updateScheduler:      &modUpdateScheduler{},
+	}
 	err = FicsitCLI.initInstallations()
 	if err != nil {
 		return fmt.Errorf("failed to initialize installations: %w", err)
 	}
+	go FicsitCLI.processOperations()
 	return nil
 }
 
@@ -59,14 +79,17 @@ func (f *ficsitCLI) StartGameRunningWatcher() {
 					break
 				}
 			}
-			wailsRuntime.EventsEmit(appCommon.AppContext, "isGameRunning", f.isGameRunning)
+			appCommon.EventsEmit("isGameRunning", f.isGameRunning)
 		}
 	}()
 }
 
 func (f *ficsitCLI) setProgress(p *Progress) {
+	if p != nil {
+		p.Operations = f.runningOperations()
+	}
 	f.progress = p
-	wailsRuntime.EventsEmit(appCommon.AppContext, "progress", p)
+	appCommon.EventsEmit("progress", p)
 }
 
 func (f *ficsitCLI) isValidInstall(path string) bool {
@@ -0,0 +1,272 @@
+package headless
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/ficsitcli"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/utils"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/websocket"
+)
+
+// How long to wait for the remote server installations to be checked
+const installationsLoadTimeout = 2 * time.Minute
+
+type command struct {
+	usage string
+	// Number of positional arguments, -1 for any number
+	minArgs int
+	maxArgs int
+	run     func(args []string) (interface{}, error)
+}
+
+var commands = map[string]command{
+	"list-installs": {
+		usage: "list-installs",
+		run:   listInstalls,
+	},
+	"select-install": {
+		usage:   "select-install <path>",
+		minArgs: 1,
+		maxArgs: 1,
+		run: func(args []string) (interface{}, error) {
+			return nil, ficsitcli.FicsitCLI.SelectInstall(args[0]) //nolint:wrapcheck
+		},
+	},
+	"install-mod": {
+		usage:   "install-mod <mod reference> [version constraint]",
+		minArgs: 1,
+		maxArgs: 2,
+		run: func(args []string) (interface{}, error) {
+			if len(args) == 2 {
+				return nil, ficsitcli.FicsitCLI.InstallModVersion(args[0], args[1]) //nolint:wrapcheck
+			}
+			return nil, ficsitcli.FicsitCLI.InstallMod(args[0]) //nolint:wrapcheck
+		},
+	},
+	"remove-mod": {
+		usage:   "remove-mod <mod reference>",
+		minArgs: 1,
+		maxArgs: 1,
+		run: func(args []string) (interface{}, error) {
+			return nil, ficsitcli.FicsitCLI.RemoveMod(args[0]) //nolint:wrapcheck
+		},
+	},
+	"enable-mod": {
+		usage:   "enable-mod <mod reference>",
+		minArgs: 1,
+		maxArgs: 1,
+		run: func(args []string) (interface{}, error) {
+			return nil, ficsitcli.FicsitCLI.EnableMod(args[0]) //nolint:wrapcheck
+		},
+	},
+	"disable-mod": {
+		usage:   "disable-mod <mod reference>",
+		minArgs: 1,
+		maxArgs: 1,
+		run: func(args []string) (interface{}, error) {
+			return nil, ficsitcli.FicsitCLI.DisableMod(args[0]) //nolint:wrapcheck
+		},
+	},
+	"list-profiles": {
+		usage: "list-profiles",
+		run: func(_ []string) (interface{}, error) {
+			return ficsitcli.FicsitCLI.GetProfiles(), nil
+		},
+	},
+	"set-profile": {
+		usage:   "set-profile <profile>",
+		minArgs: 1,
+		maxArgs: 1,
+		run: func(args []string) (interface{}, error) {
+			if ficsitcli.FicsitCLI.GetProfile(args[0]) == nil {
+				return nil, fmt.Errorf("profile %s not found", args[0])
+			}
+			return nil, ficsitcli.FicsitCLI.SetProfile(args[0]) //nolint:wrapcheck
+		},
+	},
+	"check-updates": {
+		usage: "check-updates",
+		run: func(_ []string) (interface{}, error) {
+			return ficsitcli.FicsitCLI.CheckForUpdates() //nolint:wrapcheck
+		},
+	},
+	"apply-updates": {
+		usage:   "apply-updates [mod reference...]",
+		maxArgs: -1,
+		run:     applyUpdates,
+	},
+	"diff-profiles": {
+		usage:   "diff-profiles <profile> <other profile>",
+		minArgs: 2,
+		maxArgs: 2,
+		run: func(args []string) (interface{}, error) {
+			return ficsitcli.FicsitCLI.DiffProfiles(args[0], args[1]) //nolint:wrapcheck
+		},
+	},
+	"merge-profiles": {
+		usage:   "merge-profiles <target> <source> [preferTarget|preferSource|unionHighest]",
+		minArgs: 2,
+		maxArgs: 3,
+		run: func(args []string) (interface{}, error) {
+			strategy := ficsitcli.MergeStrategyPreferTarget
+			if len(args) > 2 {
+				strategy = ficsitcli.MergeStrategy(args[2])
+			}
+			return ficsitcli.FicsitCLI.MergeProfiles(args[0], args[1], strategy) //nolint:wrapcheck
+		},
+	},
+	"export-profile": {
+		usage:   "export-profile <file>",
+		minArgs: 1,
+		maxArgs: 1,
+		run: func(args []string) (interface{}, error) {
+			return nil, ficsitcli.FicsitCLI.ExportCurrentProfileToFile(args[0]) //nolint:wrapcheck
+		},
+	},
+	"export-profile-bundle": {
+		usage:   "export-profile-bundle <file>",
+		minArgs: 1,
+		maxArgs: 1,
+		run: func(args []string) (interface{}, error) {
+			return nil, ficsitcli.FicsitCLI.ExportCurrentProfileBundleToFile(args[0]) //nolint:wrapcheck
+		},
+	},
+	"import-profile": {
+		usage:   "import-profile <name> <file, bundle, URL or share string>",
+		minArgs: 2,
+		maxArgs: 2,
+		run: func(args []string) (interface{}, error) {
+			file, err := ficsitcli.FicsitCLI.ResolveProfileSource(args[1])
+			if err != nil {
+				return nil, err //nolint:wrapcheck
+			}
+			return nil, ficsitcli.FicsitCLI.ImportProfile(args[0], file) //nolint:wrapcheck
+		},
+	},
+}
+
+type output struct {
+	Success bool        `json:"success"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// Run executes a single command without starting the GUI, writes the result to stdout as JSON,
+// and returns the exit code
+func Run(args []string) int {
+	flags := flag.NewFlagSet("headless", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	install := flags.String("install", "", "installation to run the command on, instead of the selected one")
+	if err := flags.Parse(args); err != nil {
+		return writeOutput(nil, fmt.Errorf("%w\n%s", err, usage()))
+	}
+
+	if flags.NArg() == 0 {
+		return writeOutput(nil, errors.New(usage()))
+	}
+
+	name := flags.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		return writeOutput(nil, fmt.Errorf("unknown command %s\n%s", name, usage()))
+	}
+
+	cmdArgs := flags.Args()[1:]
+	if len(cmdArgs) < cmd.minArgs || (cmd.maxArgs >= 0 && len(cmdArgs) > cmd.maxArgs) {
+		return writeOutput(nil, fmt.Errorf("usage: %s", cmd.usage))
+	}
+
+	waitForInstallations()
+
+	if *install != "" {
+		restore, err := ficsitcli.FicsitCLI.SelectInstallTemporarily(*install)
+		if err != nil {
+			return writeOutput(nil, fmt.Errorf("failed to select installation: %w", err))
+		}
+		defer restore()
+	}
+
+	slog.Info("running headless command", slog.String("command", name), slog.Any("args", cmdArgs))
+
+	result, err := cmd.run(cmdArgs)
+	return writeOutput(result, err)
+}
+
+// CheckNoRunningInstance returns a non-zero exit code, after writing the error, if the app is already running.
+// Both would write the profiles, installations and lockfiles, and overwrite each other's changes.
+func CheckNoRunningInstance() int {
+	if pid, ok := websocket.RunningInstance(); ok {
+		return writeOutput(nil, fmt.Errorf("SatisfactoryModManager is already running (pid %d), close it before running headless commands", pid))
+	}
+	return 0
+}
+
+func usage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	text := "usage: SatisfactoryModManager --headless [--install <path>] <command> [args]\ncommands:"
+	for _, name := range names {
+		text += "\n  " + commands[name].usage
+	}
+	return text
+}
+
+func writeOutput(result interface{}, err error) int {
+	out := output{
+		Success: err == nil,
+		Result:  result,
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+
+	outputJSON, marshalErr := utils.JSONMarshal(out, 2)
+	if marshalErr != nil {
+		slog.Error("failed to marshal output", slog.Any("error", marshalErr))
+		return 1
+	}
+	_, _ = os.Stdout.Write(outputJSON)
+
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// waitForInstallations waits until the remote server installations are checked, since they are loaded in the background
+func waitForInstallations() {
+	deadline := time.Now().Add(installationsLoadTimeout)
+	for time.Now().Before(deadline) {
+		loading := false
+		for _, metadata := range ficsitcli.FicsitCLI.GetInstallationsMetadata() {
+			if metadata.State == ficsitcli.InstallStateUnknown || metadata.State == ficsitcli.InstallStateLoading {
+				loading = true
+				break
+			}
+		}
+		if !loading {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	slog.Warn("timed out waiting for installations to load")
+}
+
+func listInstalls(_ []string) (interface{}, error) {
+	return ficsitcli.FicsitCLI.GetInstallationsInfo(), nil
+}
+
+func applyUpdates(args []string) (interface{}, error) {
+	return ficsitcli.FicsitCLI.UpdateAllMods(args) //nolint:wrapcheck
+}
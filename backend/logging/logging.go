@@ -14,13 +14,22 @@ import (
 )
 
 func Init() {
+	initHandlers(os.Stdout)
+}
+
+// InitHeadless logs to stderr instead of stdout, so that stdout only contains the command output
+func InitHeadless() {
+	initHandlers(os.Stderr)
+}
+
+func initHandlers(console *os.File) {
 	handlers := make([]slog.Handler, 0)
 
-	if _, err := os.Stdout.Stat(); err == nil {
-		// Only add the stdout handler if it is writable.
+	if _, err := console.Stat(); err == nil {
+		// Only add the console handler if it is writable.
 		// Otherwise, the fanout handler would have the first handler error,
 		// and will not get to use the file handler.
-		handlers = append(handlers, tint.NewHandler(os.Stdout, &tint.Options{
+		handlers = append(handlers, tint.NewHandler(console, &tint.Options{
 			Level:      settingsLogLevel{},
 			AddSource:  true,
 			TimeFormat: time.RFC3339,
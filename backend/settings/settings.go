@@ -4,11 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/mircearoata/pubgrub-go/pubgrub/semver"
 	"github.com/spf13/viper"
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/utils"
@@ -34,6 +38,32 @@ var (
 	UpdateAsk      UpdateCheckMode = "ask"
 )
 
+type WebsocketScope string
+
+var (
+	WebsocketScopeRead   WebsocketScope = "read"
+	WebsocketScopeModify WebsocketScope = "modify"
+)
+
+var AllWebsocketScopes = []struct {
+	Value  WebsocketScope
+	TSName string
+}{
+	{WebsocketScopeRead, "READ"},
+	{WebsocketScopeModify, "MODIFY"},
+}
+
+// WebsocketClient is an application the user allowed to use the local websocket server
+type WebsocketClient struct {
+	ID     string         `json:"id"`
+	Name   string         `json:"name"`
+	Origin string         `json:"origin,omitempty"`
+	Scope  WebsocketScope `json:"scope"`
+	// The token itself is only given to the client, only its hash is stored
+	TokenHash string    `json:"tokenHash"`
+	Paired    time.Time `json:"paired"`
+}
+
 type settings struct {
 	WindowPosition *utils.Position `json:"windowPosition,omitempty"`
 	Maximized      bool            `json:"maximized,omitempty"`
@@ -46,10 +76,16 @@ type settings struct {
 	FavoriteMods []string        `json:"favoriteMods,omitempty"`
 	ModFilters   SavedModFilters `json:"modFilters,omitempty"`
 
-	QueueAutoStart      bool                `json:"queueAutoStart,omitempty"`
-	IgnoredUpdates      map[string][]string `json:"ignoredUpdates,omitempty"`
-	UpdateCheckMode     UpdateCheckMode     `json:"updateCheckMode,omitempty"`
-	ViewedAnnouncements []string            `json:"viewedAnnouncements,omitempty"`
+	QueueAutoStart           bool                `json:"queueAutoStart,omitempty"`
+	IgnoredUpdates           map[string][]string `json:"ignoredUpdates,omitempty"`
+	PinnedMods               map[string][]string `json:"pinnedMods,omitempty"`
+	UnattendedUpdateInstalls []string            `json:"unattendedUpdateInstalls,omitempty"`
+	UpdateCheckMode          UpdateCheckMode     `json:"updateCheckMode,omitempty"`
+	ViewedAnnouncements      []string            `json:"viewedAnnouncements,omitempty"`
+
+	WebsocketPort           int               `json:"websocketPort,omitempty"`
+	WebsocketClients        []WebsocketClient `json:"websocketClients,omitempty"`
+	WebsocketTrustedOrigins []string          `json:"websocketTrustedOrigins,omitempty"`
 
 	Offline bool `json:"offline,omitempty"`
 
@@ -76,10 +112,15 @@ var Settings = &settings{
 		Filter: "Compatible",
 	},
 
-	QueueAutoStart:      true,
-	IgnoredUpdates:      map[string][]string{},
-	UpdateCheckMode:     UpdateOnLaunch,
-	ViewedAnnouncements: []string{},
+	QueueAutoStart:           true,
+	IgnoredUpdates:           map[string][]string{},
+	PinnedMods:               map[string][]string{},
+	UnattendedUpdateInstalls: []string{},
+	UpdateCheckMode:          UpdateOnLaunch,
+	ViewedAnnouncements:      []string{},
+
+	WebsocketClients:        []WebsocketClient{},
+	WebsocketTrustedOrigins: []string{},
 
 	Offline: false,
 
@@ -149,7 +190,7 @@ func (s *settings) SetModFiltersFilter(filter string) {
 }
 
 func (s *settings) emitFavoriteMods() {
-	wailsRuntime.EventsEmit(common.AppContext, "favoriteMods", s.FavoriteMods)
+	common.EventsEmit("favoriteMods", s.FavoriteMods)
 }
 
 func (s *settings) GetStartView() View {
@@ -192,10 +233,18 @@ func (s *settings) GetIgnoredUpdates() map[string][]string {
 	return s.IgnoredUpdates
 }
 
-func (s *settings) SetUpdateIgnore(modReference string, version string) {
+// SetUpdateIgnore ignores updates of the mod to the version, which can also be a range such as "2.x"
+func (s *settings) SetUpdateIgnore(modReference string, version string) error {
+	if _, err := semver.NewConstraint(version); err != nil {
+		return fmt.Errorf("invalid version or range %s: %w", version, err)
+	}
+	if slices.Contains(s.IgnoredUpdates[modReference], version) {
+		return nil
+	}
 	s.IgnoredUpdates[modReference] = append(s.IgnoredUpdates[modReference], version)
 	_ = SaveSettings()
-	wailsRuntime.EventsEmit(common.AppContext, "ignoredUpdates", s.IgnoredUpdates)
+	common.EventsEmit("ignoredUpdates", s.IgnoredUpdates)
+	return nil
 }
 
 func (s *settings) SetUpdateUnignore(modReference string, version string) {
@@ -212,7 +261,7 @@ func (s *settings) SetUpdateUnignore(modReference string, version string) {
 	}
 	s.IgnoredUpdates[modReference] = append(versions[:idx], versions[idx+1:]...)
 	_ = SaveSettings()
-	wailsRuntime.EventsEmit(common.AppContext, "ignoredUpdates", s.IgnoredUpdates)
+	common.EventsEmit("ignoredUpdates", s.IgnoredUpdates)
 }
 
 func (s *settings) GetUpdateCheckMode() UpdateCheckMode {
@@ -241,7 +290,83 @@ func (s *settings) SetAnnouncementViewed(announcement string) {
 	}
 	s.ViewedAnnouncements = append(s.ViewedAnnouncements, announcement)
 	_ = SaveSettings()
-	wailsRuntime.EventsEmit(common.AppContext, "viewedAnnouncements", s.ViewedAnnouncements)
+	common.EventsEmit("viewedAnnouncements", s.ViewedAnnouncements)
+}
+
+// websocketClientsLock guards Settings.WebsocketClients, which the websocket server reads and changes from its own goroutines
+var websocketClientsLock sync.RWMutex
+
+// GetWebsocketClients returns the paired websocket clients, without their token hashes
+func (s *settings) GetWebsocketClients() []WebsocketClient {
+	clients := PairedWebsocketClients()
+	for i := range clients {
+		clients[i].TokenHash = ""
+	}
+	return clients
+}
+
+// PairedWebsocketClients returns a copy of the paired websocket clients
+func PairedWebsocketClients() []WebsocketClient {
+	websocketClientsLock.RLock()
+	defer websocketClientsLock.RUnlock()
+	return slices.Clone(Settings.WebsocketClients)
+}
+
+// AddWebsocketClient stores the newly paired client
+func AddWebsocketClient(client WebsocketClient) error {
+	websocketClientsLock.Lock()
+	Settings.WebsocketClients = append(Settings.WebsocketClients, client)
+	websocketClientsLock.Unlock()
+
+	err := SaveSettings()
+	common.EventsEmit("websocketClients", Settings.GetWebsocketClients())
+	return err
+}
+
+// RemoveWebsocketClient removes the client, and returns whether it was paired
+func RemoveWebsocketClient(id string) (bool, error) {
+	websocketClientsLock.Lock()
+	idx := slices.IndexFunc(Settings.WebsocketClients, func(c WebsocketClient) bool { return c.ID == id })
+	if idx != -1 {
+		Settings.WebsocketClients = slices.Delete(Settings.WebsocketClients, idx, idx+1)
+	}
+	websocketClientsLock.Unlock()
+	if idx == -1 {
+		return false, nil
+	}
+
+	err := SaveSettings()
+	common.EventsEmit("websocketClients", Settings.GetWebsocketClients())
+	return true, err
+}
+
+func (s *settings) GetWebsocketTrustedOrigins() []string {
+	return s.WebsocketTrustedOrigins
+}
+
+// AddWebsocketTrustedOrigin allows pages of the origin, such as https://ficsit.app, to read from the websocket server without pairing
+func (s *settings) AddWebsocketTrustedOrigin(origin string) error {
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") {
+		return fmt.Errorf("invalid origin %s, expected scheme://host[:port]", origin)
+	}
+	origin = parsed.Scheme + "://" + parsed.Host
+	if slices.Contains(s.WebsocketTrustedOrigins, origin) {
+		return nil
+	}
+	s.WebsocketTrustedOrigins = append(s.WebsocketTrustedOrigins, origin)
+	_ = SaveSettings()
+	common.EventsEmit("websocketTrustedOrigins", s.WebsocketTrustedOrigins)
+	return nil
+}
+
+func (s *settings) RemoveWebsocketTrustedOrigin(origin string) {
+	if !slices.Contains(s.WebsocketTrustedOrigins, origin) {
+		return
+	}
+	s.WebsocketTrustedOrigins = slices.DeleteFunc(s.WebsocketTrustedOrigins, func(o string) bool { return o == origin })
+	_ = SaveSettings()
+	common.EventsEmit("websocketTrustedOrigins", s.WebsocketTrustedOrigins)
 }
 
 func (s *settings) GetDebug() bool {
@@ -265,7 +390,7 @@ func (s *settings) SetCacheDir(dir string) error {
 	}
 	s.CacheDir = dir
 	_ = SaveSettings()
-	wailsRuntime.EventsEmit(common.AppContext, "cacheDir", s.GetCacheDir())
+	common.EventsEmit("cacheDir", s.GetCacheDir())
 	return nil
 }
 
@@ -384,7 +509,9 @@ func LoadSettings() error {
 }
 
 func SaveSettings() error {
+	websocketClientsLock.RLock()
 	settingsFile, err := utils.JSONMarshal(Settings, 2)
+	websocketClientsLock.RUnlock()
 	if err != nil {
 		return fmt.Errorf("failed to marshal settings: %w", err)
 	}
@@ -0,0 +1,405 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"sync"
+
+	engineio_types "github.com/zishang520/engine.io/types"
+	"github.com/zishang520/socket.io/socket"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/ficsitcli"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
+)
+
+// Events forwarded to the API subscribers
+var streamedEvents = []string{
+	"progress",
+	"lockfileMods",
+	"isGameRunning",
+	"installations",
+	"installationsMetadata",
+	"selectedInstallation",
+	"selectedProfile",
+	"modUpdatesAvailable",
+}
+
+// Events are dropped for subscribers that fall this far behind
+const subscriberBufferSize = 64
+
+type event struct {
+	Name string
+	Data interface{}
+}
+
+type eventHub struct {
+	lock        sync.Mutex
+	subscribers map[chan event]bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subscribers: make(map[chan event]bool),
+	}
+}
+
+func (h *eventHub) subscribe() chan event {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	ch := make(chan event, subscriberBufferSize)
+	h.subscribers[ch] = true
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.subscribers, ch)
+}
+
+func (h *eventHub) publish(e event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			slog.Warn("api subscriber is too slow, dropping event", slog.String("event", e.Name))
+		}
+	}
+}
+
+type api struct {
+	events *eventHub
+}
+
+// registerAPI adds the control API to the server, and forwards the app events
+// both to the event stream and to the connected socket.io clients
+func registerAPI(mux *engineio_types.ServeMux, io *socket.Server) {
+	a := &api{
+		events: newEventHub(),
+	}
+
+	appCommon.AddEventListener(func(eventName string, data ...interface{}) {
+		if !slices.Contains(streamedEvents, eventName) {
+			return
+		}
+		e := event{Name: eventName, Data: eventData(data)}
+		a.events.publish(e)
+		_ = io.Sockets().Emit(e.Name, e.Data)
+	})
+
+	read := settings.WebsocketScopeRead
+	modify := settings.WebsocketScopeModify
+	mux.HandleFunc("/api/pair", a.handle(http.MethodPost, "", a.pair))
+	mux.HandleFunc("/api/installations", a.handle(http.MethodGet, read, a.getInstallations))
+	mux.HandleFunc("/api/profiles", a.handle(http.MethodGet, read, a.getProfiles))
+	mux.HandleFunc("/api/profile", a.handle(http.MethodGet, read, a.getProfile))
+	mux.HandleFunc("/api/lockfile", a.handle(http.MethodGet, read, a.getLockfile))
+	mux.HandleFunc("/api/updates", a.handle(http.MethodGet, read, a.getUpdates))
+	mux.HandleFunc("/api/mods/install", a.handle(http.MethodPost, modify, a.installMod))
+	mux.HandleFunc("/api/mods/remove", a.handle(http.MethodPost, modify, a.removeMod))
+	mux.HandleFunc("/api/mods/enable", a.handle(http.MethodPost, modify, a.enableMod))
+	mux.HandleFunc("/api/mods/disable", a.handle(http.MethodPost, modify, a.disableMod))
+	mux.HandleFunc("/api/mods/update", a.handle(http.MethodPost, modify, a.updateMods))
+	mux.HandleFunc("/api/events", a.streamEvents)
+}
+
+func eventData(data []interface{}) interface{} {
+	if len(data) == 1 {
+		return data[0]
+	}
+	return data
+}
+
+// apiError is returned by handlers for errors caused by the request
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusNotFound, message: fmt.Sprintf(format, args...)}
+}
+
+type response struct {
+	Success bool        `json:"success"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// handle checks the method and the client's access before calling the handler.
+// An empty scope only requires the origin to be trusted.
+func (a *api) handle(method string, scope settings.WebsocketScope, handler func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowCORS(w, r, method) {
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeResponse(w, http.StatusMethodNotAllowed, response{Error: "method not allowed"})
+			return
+		}
+
+		var err error
+		if scope == "" {
+			err = checkOrigin(r.Header.Get("Origin"))
+		} else {
+			_, err = Clients.authorize(r.Header.Get("Origin"), requestToken(r), scope)
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		result, err := handler(r)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeResponse(w, http.StatusOK, response{Success: true, Result: result})
+	}
+}
+
+// allowCORS adds the CORS headers for trusted origins, and answers preflight requests.
+// Returns whether the request should be handled further.
+func allowCORS(w http.ResponseWriter, r *http.Request, method string) bool {
+	origin := r.Header.Get("Origin")
+	if origin != "" && isTrustedOrigin(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", method)
+		w.Header().Add("Vary", "Origin")
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var requestErr *apiError
+	if errors.As(err, &requestErr) {
+		status = requestErr.status
+	} else {
+		slog.Error("api request failed", slog.String("path", r.URL.Path), slog.Any("error", err))
+	}
+	writeResponse(w, status, response{Error: err.Error()})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.Error("failed to write api response", slog.Any("error", err))
+	}
+}
+
+// decodeBody only accepts JSON bodies. Browsers send other content types cross-origin without a preflight,
+// so requiring JSON keeps web pages from posting to the API unless the origin is allowed by CORS.
+func decodeBody(r *http.Request, body interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return &apiError{status: http.StatusUnsupportedMediaType, message: "the request body must be application/json"}
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(body); err != nil {
+		return badRequest("invalid request body: %s", err.Error())
+	}
+	return nil
+}
+
+type pairRequest struct {
+	Name  string                  `json:"name"`
+	Scope settings.WebsocketScope `json:"scope"`
+}
+
+// pair waits until the user approves or denies the client, and returns the token the client must present from then on
+func (a *api) pair(r *http.Request) (interface{}, error) {
+	var body pairRequest
+	if err := decodeBody(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Scope == "" {
+		body.Scope = settings.WebsocketScopeRead
+	}
+	return Clients.pair(r.Context(), body.Name, r.Header.Get("Origin"), body.Scope)
+}
+
+func (a *api) getInstallations(_ *http.Request) (interface{}, error) {
+	return ficsitcli.FicsitCLI.GetInstallationsInfo(), nil
+}
+
+func (a *api) getProfiles(_ *http.Request) (interface{}, error) {
+	return ficsitcli.FicsitCLI.GetProfiles(), nil
+}
+
+func (a *api) getProfile(r *http.Request) (interface{}, error) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		selected := ficsitcli.FicsitCLI.GetSelectedProfile()
+		if selected == nil {
+			return nil, notFound("no installation selected")
+		}
+		name = *selected
+	}
+	profile := ficsitcli.FicsitCLI.GetProfile(name)
+	if profile == nil {
+		return nil, notFound("profile %s not found", name)
+	}
+	return profile, nil
+}
+
+func (a *api) getLockfile(r *http.Request) (interface{}, error) {
+	path := r.URL.Query().Get("install")
+	if path == "" {
+		selected := ficsitcli.FicsitCLI.GetSelectedInstall()
+		if selected == nil {
+			return nil, notFound("no installation selected")
+		}
+		path = selected.Path
+	}
+	if ficsitcli.FicsitCLI.GetInstallation(path) == nil {
+		return nil, notFound("installation %s not found", path)
+	}
+	lockfile, err := ficsitcli.FicsitCLI.GetInstallLockfile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lockfile: %w", err)
+	}
+	return lockfile, nil
+}
+
+func (a *api) getUpdates(_ *http.Request) (interface{}, error) {
+	return ficsitcli.FicsitCLI.GetModUpdates(), nil
+}
+
+type modRequest struct {
+	Mod     string `json:"mod"`
+	Version string `json:"version,omitempty"`
+}
+
+func (a *api) decodeModRequest(r *http.Request) (*modRequest, error) {
+	var body modRequest
+	if err := decodeBody(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Mod == "" {
+		return nil, badRequest("mod is required")
+	}
+	return &body, nil
+}
+
+func (a *api) installMod(r *http.Request) (interface{}, error) {
+	body, err := a.decodeModRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	if body.Version != "" {
+		return nil, ficsitcli.FicsitCLI.InstallModVersion(body.Mod, body.Version) //nolint:wrapcheck
+	}
+	return nil, ficsitcli.FicsitCLI.InstallMod(body.Mod) //nolint:wrapcheck
+}
+
+func (a *api) removeMod(r *http.Request) (interface{}, error) {
+	body, err := a.decodeModRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, ficsitcli.FicsitCLI.RemoveMod(body.Mod) //nolint:wrapcheck
+}
+
+func (a *api) enableMod(r *http.Request) (interface{}, error) {
+	body, err := a.decodeModRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, ficsitcli.FicsitCLI.EnableMod(body.Mod) //nolint:wrapcheck
+}
+
+func (a *api) disableMod(r *http.Request) (interface{}, error) {
+	body, err := a.decodeModRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, ficsitcli.FicsitCLI.DisableMod(body.Mod) //nolint:wrapcheck
+}
+
+type updateRequest struct {
+	// Mods to update, or every available update if empty
+	Mods []string `json:"mods"`
+}
+
+func (a *api) updateMods(r *http.Request) (interface{}, error) {
+	var body updateRequest
+	if err := decodeBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return ficsitcli.FicsitCLI.UpdateAllMods(body.Mods) //nolint:wrapcheck
+}
+
+// streamEvents sends the app events to the client as server-sent events until it disconnects
+func (a *api) streamEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowCORS(w, r, http.MethodGet) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeResponse(w, http.StatusMethodNotAllowed, response{Error: "method not allowed"})
+		return
+	}
+	if _, err := Clients.authorize(r.Header.Get("Origin"), requestToken(r), settings.WebsocketScopeRead); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeResponse(w, http.StatusInternalServerError, response{Error: "streaming not supported"})
+		return
+	}
+
+	ch := a.events.subscribe()
+	defer a.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			data, err := json.Marshal(e.Data)
+			if err != nil {
+				slog.Error("failed to marshal event", slog.String("event", e.Name), slog.Any("error", err))
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
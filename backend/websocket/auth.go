@@ -0,0 +1,322 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zishang520/socket.io/socket"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
+)
+
+// How long a pairing request waits for the user to approve it
+const pairingTimeout = 2 * time.Minute
+
+// Upper bound on the pairing requests waiting for the user at once
+const maxPendingPairings = 5
+
+const maxClientNameLength = 64
+
+type PairingRequest struct {
+	ID     string                  `json:"id"`
+	Name   string                  `json:"name"`
+	Origin string                  `json:"origin,omitempty"`
+	Scope  settings.WebsocketScope `json:"scope"`
+}
+
+type pendingPairing struct {
+	request PairingRequest
+	result  chan *settings.WebsocketScope
+}
+
+type pairingResponse struct {
+	ClientID string                  `json:"clientId"`
+	Token    string                  `json:"token"`
+	Scope    settings.WebsocketScope `json:"scope"`
+}
+
+// clientAccess is what an authorized request or socket is allowed to do
+type clientAccess struct {
+	// Empty for trusted origins using the server without pairing
+	clientID string
+	scope    settings.WebsocketScope
+}
+
+type clientManager struct {
+	lock    sync.Mutex
+	pending map[string]*pendingPairing
+	io      *socket.Server
+}
+
+// Clients handles pairing the applications that use the websocket server, and checks their access
+var Clients = &clientManager{
+	pending: make(map[string]*pendingPairing),
+}
+
+func randomString(bytes int) (string, error) {
+	b := make([]byte, bytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func isValidScope(scope settings.WebsocketScope) bool {
+	return scope == settings.WebsocketScopeRead || scope == settings.WebsocketScopeModify
+}
+
+func hasScope(granted settings.WebsocketScope, required settings.WebsocketScope) bool {
+	return granted == settings.WebsocketScopeModify || granted == required
+}
+
+func clientRoom(clientID string) socket.Room {
+	return socket.Room("client:" + clientID)
+}
+
+func isTrustedOrigin(origin string) bool {
+	return slices.Contains(settings.Settings.WebsocketTrustedOrigins, origin)
+}
+
+// checkOrigin rejects requests made by web pages that are not trusted.
+// Requests without an origin do not come from a browser, so they cannot be made by a web page.
+func checkOrigin(origin string) error {
+	if origin != "" && !isTrustedOrigin(origin) {
+		return &apiError{status: http.StatusForbidden, message: fmt.Sprintf("origin %s is not trusted", origin)}
+	}
+	return nil
+}
+
+// authorize checks that the origin is allowed to use the server, and that the token grants the required scope.
+// Trusted origins can read without a token, everything else requires pairing first.
+func (m *clientManager) authorize(origin string, token string, required settings.WebsocketScope) (*clientAccess, error) {
+	if err := checkOrigin(origin); err != nil {
+		return nil, err
+	}
+
+	if token == "" {
+		if origin != "" && required == settings.WebsocketScopeRead {
+			return &clientAccess{scope: settings.WebsocketScopeRead}, nil
+		}
+		return nil, &apiError{status: http.StatusUnauthorized, message: "a token is required, pair the client first"}
+	}
+
+	tokenHash := hashToken(token)
+	for _, client := range settings.PairedWebsocketClients() {
+		if subtle.ConstantTimeCompare([]byte(client.TokenHash), []byte(tokenHash)) != 1 {
+			continue
+		}
+		if !hasScope(client.Scope, required) {
+			return nil, &apiError{status: http.StatusForbidden, message: fmt.Sprintf("client is not allowed to %s", required)}
+		}
+		return &clientAccess{clientID: client.ID, scope: client.Scope}, nil
+	}
+	return nil, &apiError{status: http.StatusUnauthorized, message: "invalid token"}
+}
+
+// pair asks the user to approve the client, and waits for the answer
+func (m *clientManager) pair(ctx context.Context, name string, origin string, scope settings.WebsocketScope) (*pairingResponse, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxClientNameLength {
+		return nil, badRequest("name must be between 1 and %d characters", maxClientNameLength)
+	}
+	if !isValidScope(scope) {
+		return nil, badRequest("invalid scope %s", scope)
+	}
+
+	id, err := randomString(8)
+	if err != nil {
+		return nil, err
+	}
+
+	pairing := &pendingPairing{
+		request: PairingRequest{
+			ID:     id,
+			Name:   name,
+			Origin: origin,
+			Scope:  scope,
+		},
+		result: make(chan *settings.WebsocketScope, 1),
+	}
+
+	m.lock.Lock()
+	if len(m.pending) >= maxPendingPairings {
+		m.lock.Unlock()
+		return nil, &apiError{status: http.StatusTooManyRequests, message: "too many pending pairing requests"}
+	}
+	m.pending[id] = pairing
+	m.lock.Unlock()
+	m.emitPendingPairings()
+
+	defer func() {
+		m.lock.Lock()
+		delete(m.pending, id)
+		m.lock.Unlock()
+		m.emitPendingPairings()
+	}()
+
+	slog.Info("websocket client requested pairing", slog.String("name", name), slog.String("origin", origin), slog.String("scope", string(scope)))
+
+	timeout := time.NewTimer(pairingTimeout)
+	defer timeout.Stop()
+
+	var grantedScope *settings.WebsocketScope
+	select {
+	case grantedScope = <-pairing.result:
+	case <-timeout.C:
+		return nil, &apiError{status: http.StatusForbidden, message: "pairing request timed out"}
+	case <-ctx.Done():
+		return nil, fmt.Errorf("pairing request cancelled: %w", ctx.Err())
+	}
+	if grantedScope == nil {
+		return nil, &apiError{status: http.StatusForbidden, message: "pairing request denied"}
+	}
+
+	token, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = settings.AddWebsocketClient(settings.WebsocketClient{
+		ID:        id,
+		Name:      name,
+		Origin:    origin,
+		Scope:     *grantedScope,
+		TokenHash: hashToken(token),
+		Paired:    time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	slog.Info("websocket client paired", slog.String("name", name), slog.String("id", id), slog.String("scope", string(*grantedScope)))
+
+	return &pairingResponse{
+		ClientID: id,
+		Token:    token,
+		Scope:    *grantedScope,
+	}, nil
+}
+
+func (m *clientManager) emitPendingPairings() {
+	appCommon.EventsEmit("websocketPairingRequests", m.GetPendingPairings())
+}
+
+// GetPendingPairings returns the pairing requests waiting for the user to approve them
+func (m *clientManager) GetPendingPairings() []PairingRequest {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	requests := make([]PairingRequest, 0, len(m.pending))
+	for _, pairing := range m.pending {
+		requests = append(requests, pairing.request)
+	}
+	slices.SortFunc(requests, func(a, b PairingRequest) int { return strings.Compare(a.Name, b.Name) })
+	return requests
+}
+
+// ApprovePairing pairs the client with the scope, which can be narrower than the requested one
+func (m *clientManager) ApprovePairing(id string, scope settings.WebsocketScope) error {
+	if !isValidScope(scope) {
+		return fmt.Errorf("invalid scope %s", scope)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pairing, ok := m.pending[id]
+	if !ok {
+		return fmt.Errorf("pairing request %s not found", id)
+	}
+	if !hasScope(pairing.request.Scope, scope) {
+		scope = pairing.request.Scope
+	}
+	select {
+	case pairing.result <- &scope:
+	default:
+	}
+	return nil
+}
+
+func (m *clientManager) DenyPairing(id string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pairing, ok := m.pending[id]
+	if !ok {
+		return
+	}
+	select {
+	case pairing.result <- nil:
+	default:
+	}
+}
+
+// RevokeClient removes the client's access, and disconnects it if it is connected
+func (m *clientManager) RevokeClient(id string) error {
+	found, err := settings.RemoveWebsocketClient(id)
+	if !found {
+		return fmt.Errorf("client %s not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	m.lock.Lock()
+	io := m.io
+	m.lock.Unlock()
+	if io != nil {
+		io.In(clientRoom(id)).DisconnectSockets(true)
+	}
+	return nil
+}
+
+// socketMiddleware only lets in the socket.io clients with read access
+func (m *clientManager) socketMiddleware(client *socket.Socket, next func(*socket.ExtendedError)) {
+	handshake := client.Handshake()
+	origin := handshake.Headers.Peek("Origin")
+
+	token := ""
+	if auth, ok := handshake.Auth.(map[string]any); ok {
+		token, _ = auth["token"].(string)
+	}
+	if token == "" {
+		token = handshake.Query.Peek("token")
+	}
+
+	access, err := m.authorize(origin, token, settings.WebsocketScopeRead)
+	if err != nil {
+		slog.Warn("rejected websocket connection", slog.String("origin", origin), slog.Any("error", err))
+		next(socket.NewExtendedError(err.Error(), nil))
+		return
+	}
+	client.SetData(access)
+	if access.clientID != "" {
+		client.Join(clientRoom(access.clientID))
+	}
+	next(nil)
+}
+
+// requestToken returns the token from the Authorization header, or from the query for clients such as EventSource that cannot set headers
+func requestToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
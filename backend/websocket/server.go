@@ -0,0 +1,256 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/utils"
+)
+
+var discoveryFileName = "websocket.json"
+
+const shutdownTimeout = 5 * time.Second
+
+type ServerStatus struct {
+	Running       bool `json:"running"`
+	Port          int  `json:"port,omitempty"`
+	PreferredPort int  `json:"preferredPort"`
+	// Whether the preferred port could not be used, so another one was picked
+	Fallback bool   `json:"fallback"`
+	Error    string `json:"error,omitempty"`
+}
+
+// discoveryFile lets local tools find the running instance and how to authenticate with it
+type discoveryFile struct {
+	PID       int           `json:"pid"`
+	Version   string        `json:"version"`
+	Port      int           `json:"port"`
+	URL       string        `json:"url"`
+	SocketIO  string        `json:"socketIo"`
+	API       string        `json:"api"`
+	Auth      discoveryAuth `json:"auth"`
+	StartedAt string        `json:"startedAt"`
+}
+
+// discoveryAuth describes how to get and present a token. The tokens themselves are never written to disk.
+type discoveryAuth struct {
+	// POST {"name", "scope"} to get a token once the user approves the client
+	PairURL string   `json:"pairUrl"`
+	Scopes  []string `json:"scopes"`
+	// HTTP requests send "Authorization: Bearer <token>"
+	Header string `json:"header"`
+	Scheme string `json:"scheme"`
+	// socket.io clients send the token in this field of the handshake auth
+	SocketIOField string `json:"socketIoField"`
+}
+
+type server struct {
+	lock       sync.Mutex
+	handler    http.Handler
+	httpServer *http.Server
+	status     ServerStatus
+}
+
+var Server = &server{}
+
+func preferredPort() int {
+	if settings.Settings.WebsocketPort != 0 {
+		return settings.Settings.WebsocketPort
+	}
+	return viper.GetInt("websocket-port")
+}
+
+func (s *server) start(handler http.Handler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.handler = handler
+	s.listen()
+}
+
+// listen binds the preferred port, falling back to any free port. Must be called with the lock held.
+func (s *server) listen() {
+	l := slog.With(slog.String("task", "websocketListen"))
+
+	port := preferredPort()
+	s.status = ServerStatus{PreferredPort: port}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)))
+	if err != nil {
+		l.Warn("failed to listen on preferred port, using a free port instead", slog.Int("port", port), slog.Any("error", err))
+		s.status.Fallback = true
+		listener, err = net.Listen("tcp", "localhost:0")
+	}
+	if err != nil {
+		l.Error("failed to start websocket server", slog.Any("error", err))
+		s.status.Error = err.Error()
+		s.emitStatus()
+		removeDiscoveryFile()
+		return
+	}
+
+	s.status.Running = true
+	s.status.Port = listener.Addr().(*net.TCPAddr).Port
+	s.httpServer = &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	httpServer := s.httpServer
+	go func() {
+		err := httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			l.Error("websocket server stopped", slog.Any("error", err))
+			s.lock.Lock()
+			if s.httpServer == httpServer {
+				s.status.Running = false
+				s.status.Error = err.Error()
+				s.emitStatus()
+			}
+			s.lock.Unlock()
+		}
+	}()
+
+	l.Info("websocket server listening", slog.Int("port", s.status.Port))
+
+	if err := writeDiscoveryFile(s.status.Port); err != nil {
+		l.Error("failed to write discovery file", slog.Any("error", err))
+	}
+	s.emitStatus()
+}
+
+// stop closes the server. Must be called with the lock held.
+func (s *server) stop() {
+	if s.httpServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		slog.Warn("failed to shut down websocket server cleanly", slog.Any("error", err))
+		_ = s.httpServer.Close()
+	}
+	s.httpServer = nil
+	s.status.Running = false
+}
+
+// Shutdown stops the server and removes the discovery file
+func (s *server) Shutdown() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stop()
+	removeDiscoveryFile()
+}
+
+func (s *server) emitStatus() {
+	appCommon.EventsEmit("websocketServerStatus", s.status)
+}
+
+func (s *server) GetServerStatus() ServerStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.status
+}
+
+// SetPort changes the preferred port, 0 for the default one, and restarts the server on it
+func (s *server) SetPort(port int) error {
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("invalid port %d", port)
+	}
+
+	settings.Settings.WebsocketPort = port
+	err := settings.SaveSettings()
+	if err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.handler == nil {
+		// Not started yet, the port is used when it starts
+		return nil
+	}
+	s.stop()
+	s.listen()
+	if s.status.Error != "" {
+		return fmt.Errorf("failed to start websocket server: %s", s.status.Error)
+	}
+	return nil
+}
+
+func discoveryFilePath() string {
+	return filepath.Join(viper.GetString("smm-local-dir"), discoveryFileName)
+}
+
+func writeDiscoveryFile(port int) error {
+	baseURL := "http://localhost:" + strconv.Itoa(port)
+	discovery := discoveryFile{
+		PID:      os.Getpid(),
+		Version:  viper.GetString("version"),
+		Port:     port,
+		URL:      baseURL,
+		SocketIO: baseURL + "/socket.io",
+		API:      baseURL + "/api",
+		Auth: discoveryAuth{
+			PairURL:       baseURL + "/api/pair",
+			Scopes:        []string{string(settings.WebsocketScopeRead), string(settings.WebsocketScopeModify)},
+			Header:        "Authorization",
+			Scheme:        "Bearer",
+			SocketIOField: "token",
+		},
+		StartedAt: time.Now().Format(time.RFC3339),
+	}
+
+	discoveryJSON, err := utils.JSONMarshal(discovery, 2)
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovery file: %w", err)
+	}
+	err = os.WriteFile(discoveryFilePath(), discoveryJSON, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write discovery file: %w", err)
+	}
+	return nil
+}
+
+// RunningInstance returns the PID of another running instance of the app, found through its discovery file
+func RunningInstance() (int, bool) {
+	data, err := os.ReadFile(discoveryFilePath())
+	if err != nil {
+		return 0, false
+	}
+	var discovery discoveryFile
+	if err := json.Unmarshal(data, &discovery); err != nil || discovery.PID == os.Getpid() || discovery.Port == 0 {
+		return 0, false
+	}
+
+	// The file is left behind if the app crashes, so it only counts if the server still accepts connections
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.Itoa(discovery.Port)), time.Second)
+	if err != nil {
+		return 0, false
+	}
+	_ = conn.Close()
+	return discovery.PID, true
+}
+
+func removeDiscoveryFile() {
+	err := os.Remove(discoveryFilePath())
+	if err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to remove discovery file", slog.Any("error", err))
+	}
+}
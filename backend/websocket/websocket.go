@@ -3,22 +3,28 @@ package websocket
 import (
 	"log/slog"
 
-	"github.com/spf13/viper"
 	engineio_types "github.com/zishang520/engine.io/types"
 	"github.com/zishang520/socket.io/socket"
 
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/ficsitcli"
 )
 
+// ListenAndServeWebsocket starts the websocket server on the preferred port, or on a free port if it is taken
 func ListenAndServeWebsocket() {
 	httpServer := engineio_types.CreateServer(nil)
 	options := &socket.ServerOptions{}
 	options.SetCors(&engineio_types.Cors{
-		Origin: true, // Allow any origin
+		Origin: true, // The trusted origins can change at runtime, so they are checked by the middleware instead
 	})
 	io := socket.NewServer(nil, options)
+	io.Use(Clients.socketMiddleware)
 	httpServer.Handle("/socket.io", io.ServeHandler(nil))
 
+	Clients.lock.Lock()
+	Clients.io = io
+	Clients.lock.Unlock()
+	registerAPI(httpServer.ServeMux, io)
+
 	_ = io.On("connection", func(data ...any) {
 		client := data[0].(*socket.Socket)
 		_ = client.On("installedMods", func(datas ...any) {
@@ -39,5 +45,5 @@ func ListenAndServeWebsocket() {
 		})
 	})
 
-	httpServer.Listen("localhost:"+viper.GetString("websocket-port"), nil)
+	Server.start(httpServer)
 }
@@ -8,6 +8,8 @@ import CacheLocationPicker from './settings/CacheLocationPicker.svelte';
 import SMMUpdateDownload from './smmUpdate/SMMUpdateDownload.svelte';
 import SMMUpdateReady from './smmUpdate/SMMUpdateReady.svelte';
 import UpdatesModal from './updates/UpdatesModal.svelte';
+import WebsocketClients from './websocket/WebsocketClients.svelte';
+import WebsocketPairing from './websocket/WebsocketPairing.svelte';
 
 // We can only store here modals (or modal instances) that do not require additional props
 export const modalRegistry = {
@@ -19,5 +21,7 @@ export const modalRegistry = {
   modUpdates: { ref: UpdatesModal } as ModalComponent,
   smmUpdateDownload: { ref: SMMUpdateDownload } as ModalComponent,
   smmUpdateReady: { ref: SMMUpdateReady } as ModalComponent,
+  websocketClients: { ref: WebsocketClients } as ModalComponent,
+  websocketPairing: { ref: WebsocketPairing } as ModalComponent,
 };
 							
\ No newline at end of file
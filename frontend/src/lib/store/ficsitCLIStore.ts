@@ -1,12 +1,13 @@
-import { derived, writable } from 'svelte/store';
+import { derived, readable, writable } from 'svelte/store';
 
 import { isLaunchingGame } from './generalStore';
 import { ignoredUpdates } from './settingsStore';
 import { binding, bindingTwoWay } from './wailsStoreBindings';
 
-import { CheckForUpdates, GetInstallations, GetInstallationsMetadata, GetInvalidInstalls, GetModsEnabled, GetProfiles, GetRemoteInstallations, GetSelectedInstall, GetSelectedInstallLockfileMods, GetSelectedInstallProfileMods, GetSelectedProfile, SelectInstall, SetModsEnabled, SetProfile } from '$wailsjs/go/ficsitcli/ficsitCLI';
+import { CheckForUpdates, GetInstallProgress, GetInstallations, GetInstallationsMetadata, GetInvalidInstalls, GetModUpdates, GetModsEnabled, GetOperations, GetPinnedMods, GetProfiles, GetRemoteInstallations, GetSelectedInstall, GetSelectedInstallLockfileMods, GetSelectedInstallProfileMods, GetSelectedProfile, GetUnattendedUpdateInstalls, SelectInstall, SetModsEnabled, SetProfile } from '$wailsjs/go/ficsitcli/ficsitCLI';
 import { type cli, ficsitcli } from '$wailsjs/go/models';
 import { GetFavoriteMods } from '$wailsjs/go/settings/settings';
+import { EventsOn } from '$wailsjs/runtime/runtime';
 
 export const invalidInstalls = binding([], { initialGet: GetInvalidInstalls });
 
@@ -35,16 +36,54 @@ export interface LockedMod {
   dependencies: { [id: string]: string };
 }
 
+export const pinnedMods = binding<string[]>([], { initialGet: () => GetSelectedProfile().then((p) => (p ? GetPinnedMods(p) : [])), updateEvent: 'pinnedMods', allowNull: false });
+
 export const lockfileMods = binding({}, { initialGet: GetSelectedInstallLockfileMods, updateEvent: 'lockfileMods', allowNull: false });
 
+export interface CountProgress {
+  completed: number;
+  total: number;
+}
+
+export interface ModProgress {
+  modReference: string;
+  version: string;
+  download: CountProgress;
+  extract: CountProgress;
+  downloading: boolean;
+  complete: boolean;
+}
+
 export interface Progress {
   item: string;
+  phase: ficsitcli.ProgressPhase;
   progress: number;
-  message: string;
+  mods: CountProgress;
+  bytes: CountProgress;
+  speed: number;
+  eta: number;
+  modProgress: ModProgress[] | null;
+  installations: CountProgress;
+  operations: string[];
 }
 
 export const progress = binding<Progress | null>(null, { updateEvent: 'progress' });
 
+export const installProgress = readable<Record<string, Progress>>({}, (set, update) => {
+  GetInstallProgress().then((p) => set(p as Record<string, Progress>));
+  return EventsOn('installProgress', (install: string, p: Progress | null) => {
+    update((current) => {
+      const { [install]: _, ...rest } = current;
+      return p ? { ...rest, [install]: p } : rest;
+    });
+  });
+});
+
+export const modUpdates = binding<Record<string, ficsitcli.Update[]>>({}, { initialGet: GetModUpdates, updateEvent: 'modUpdatesAvailable', allowNull: false });
+export const unattendedUpdateInstalls = binding<string[]>([], { initialGet: GetUnattendedUpdateInstalls, updateEvent: 'unattendedUpdateInstalls', allowNull: false });
+
+export const operations = binding<ficsitcli.Operation[]>([], { initialGet: GetOperations, updateEvent: 'operations', allowNull: false });
+
 export const favoriteMods = binding<string[]>([], { updateEvent: 'favoriteMods', initialGet: GetFavoriteMods });
 
 export const isGameRunning = binding(false, { updateEvent: 'isGameRunning', allowNull: false });
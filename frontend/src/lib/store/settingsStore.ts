@@ -3,7 +3,10 @@ import { binding, bindingTwoWay, bindingTwoWayNoExcept } from './wailsStoreBindi
 import { GetVersion } from '$lib/generated/wailsjs/go/app/app';
 import type { LaunchButtonType, ViewType } from '$lib/wailsTypesExtensions';
 import { GetOffline, SetOffline } from '$wailsjs/go/ficsitcli/ficsitCLI';
-import { GetCacheDir, GetDebug, GetIgnoredUpdates, GetKonami, GetLaunchButton, GetQueueAutoStart, GetStartView, GetUpdateCheckMode, GetViewedAnnouncements, SetCacheDir, SetDebug, SetKonami, SetLaunchButton, SetQueueAutoStart, SetStartView, SetUpdateCheckMode } from '$wailsjs/go/settings/settings';
+import type { settings, websocket } from '$wailsjs/go/models';
+import { GetCacheDir, GetDebug, GetIgnoredUpdates, GetKonami, GetLaunchButton, GetQueueAutoStart, GetStartView, GetUpdateCheckMode, GetViewedAnnouncements, GetWebsocketClients, GetWebsocketTrustedOrigins, SetCacheDir, SetDebug, SetKonami, SetLaunchButton, SetQueueAutoStart, SetStartView, SetUpdateCheckMode } from '$wailsjs/go/settings/settings';
+import { GetPendingPairings } from '$wailsjs/go/websocket/clientManager';
+import { GetServerStatus } from '$wailsjs/go/websocket/server';
 
 export const startView = bindingTwoWayNoExcept<ViewType | null>(null, { initialGet: GetStartView }, { updateFunction: SetStartView });
 
@@ -26,3 +29,11 @@ export const cacheDir = bindingTwoWay<string, null>(null, { initialGet: GetCache
 export const version = binding<string>('0.0.0', { initialGet: GetVersion });
 
 export const debug = bindingTwoWayNoExcept<boolean>(false, { initialGet: GetDebug }, { updateFunction: SetDebug });
+
+export const websocketServerStatus = binding<websocket.ServerStatus | null>(null, { initialGet: GetServerStatus, updateEvent: 'websocketServerStatus' });
+
+export const websocketClients = binding<settings.WebsocketClient[]>([], { initialGet: GetWebsocketClients, updateEvent: 'websocketClients' });
+
+export const websocketTrustedOrigins = binding<string[]>([], { initialGet: GetWebsocketTrustedOrigins, updateEvent: 'websocketTrustedOrigins' });
+
+export const websocketPairingRequests = binding<websocket.PairingRequest[]>([], { initialGet: GetPendingPairings, updateEvent: 'websocketPairingRequests' });
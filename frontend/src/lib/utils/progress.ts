@@ -0,0 +1,27 @@
+import { bytesToAppropriate, secondsToAppropriate } from './dataFormats';
+
+import type { Progress } from '$lib/store/ficsitCLIStore';
+import { ficsitcli } from '$wailsjs/go/models';
+
+export function formatProgress(progress: Progress): string {
+  switch (progress.phase) {
+    case ficsitcli.ProgressPhase.RESOLVING:
+      return 'Finding the best versions to install';
+    case ficsitcli.ProgressPhase.REMOVING:
+      return 'Removing mods that are no longer needed';
+    case ficsitcli.ProgressPhase.DOWNLOADING:
+      return `Downloading ${progress.mods.completed}/${progress.mods.total} mods: ${formatTransfer(progress)}`;
+    case ficsitcli.ProgressPhase.EXTRACTING:
+      return `Extracting ${progress.mods.completed}/${progress.mods.total} mods: ${formatTransfer(progress)}`;
+    case ficsitcli.ProgressPhase.ROLLING_BACK:
+      return 'Rolling back changes';
+    case ficsitcli.ProgressPhase.APPLYING:
+      return `Applying changes: ${progress.installations.completed}/${progress.installations.total} installations done`;
+  }
+  return '';
+}
+
+function formatTransfer(progress: Progress): string {
+  const eta = progress.eta === 0 ? 'soon™' : secondsToAppropriate(progress.eta);
+  return `${bytesToAppropriate(progress.bytes.completed)}/${bytesToAppropriate(progress.bytes.total)}, ${bytesToAppropriate(progress.speed)}/s, ${eta}`;
+}
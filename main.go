@@ -21,6 +21,7 @@ import (
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/autoupdate"
 	appCommon "github.com/satisfactorymodding/SatisfactoryModManager/backend/common"
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/ficsitcli"
+	"github.com/satisfactorymodding/SatisfactoryModManager/backend/headless"
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/installfinders/common"
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/logging"
 	"github.com/satisfactorymodding/SatisfactoryModManager/backend/settings"
@@ -40,15 +41,30 @@ var (
 )
 
 func main() {
-	logging.Init()
+	// Headless mode runs a single command and prints the result, without starting the GUI
+	headlessMode := len(os.Args) > 1 && os.Args[1] == "--headless"
+
+	if headlessMode {
+		logging.InitHeadless()
+	} else {
+		logging.Init()
+	}
+
+	if headlessMode {
+		if code := headless.CheckNoRunningInstance(); code != 0 {
+			os.Exit(code)
+		}
+	}
 
 	autoupdate.Init()
 
 	err := settings.LoadSettings()
 	if err != nil {
 		slog.Error("failed to load settings", slog.Any("error", err))
-		// Cannot use wails message dialogs here yet, because they expect a frontend to exist
-		_ = dialog.Error("Failed to load settings: %s", err.Error())
+		if !headlessMode {
+			// Cannot use wails message dialogs here yet, because they expect a frontend to exist
+			_ = dialog.Error("Failed to load settings: %s", err.Error())
+		}
 		os.Exit(1)
 	}
 
@@ -64,10 +80,16 @@ func main() {
 	err = ficsitcli.Init()
 	if err != nil {
 		slog.Error("failed to initialize ficsit-cli", slog.Any("error", err))
-		_ = dialog.Error("Failed to initialize ficsit-cli: %s", err.Error())
+		if !headlessMode {
+			_ = dialog.Error("Failed to initialize ficsit-cli: %s", err.Error())
+		}
 		os.Exit(1)
 	}
 
+	if headlessMode {
+		os.Exit(headless.Run(os.Args[2:]))
+	}
+
 	windowStartState := options.Normal
 	if settings.Settings.Maximized {
 		windowStartState = options.Maximised
@@ -106,21 +128,31 @@ func main() {
 		OnDomReady: func(ctx context.Context) {
 			backend.ProcessArguments(os.Args[1:]) //nolint:contextcheck
 			autoupdate.Updater.CheckInterval(5 * time.Minute)
+			ficsitcli.FicsitCLI.StartModUpdateChecks(30 * time.Minute)
 		},
 		OnShutdown: func(ctx context.Context) {
 			app.App.StopWindowWatcher()
+			websocket.Server.Shutdown()
 		},
 		Bind: []interface{}{
 			app.App,
 			ficsitcli.FicsitCLI,
 			autoupdate.Updater,
 			settings.Settings,
+			websocket.Clients,
+			websocket.Server,
 		},
 		EnumBind: []interface{}{
 			common.AllInstallTypes,
 			common.AllBranches,
 			common.AllLocationTypes,
 			ficsitcli.AllInstallationStates,
+			ficsitcli.AllChangeActions,
+			ficsitcli.AllProgressPhases,
+			ficsitcli.AllHeldBackReasonTypes,
+			ficsitcli.AllConflictSuggestionTypes,
+			ficsitcli.AllMergeStrategies,
+			settings.AllWebsocketScopes,
 		},
 		Logger: backend.WailsZeroLogLogger{},
 	})